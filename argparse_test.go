@@ -1,7 +1,20 @@
 package argparse_test
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/skillian/argparse"
 	"github.com/skillian/errors"
@@ -11,7 +24,9 @@ func TestArgparse(t *testing.T) {
 	t.Parallel()
 
 	p := argparse.MustNewArgumentParser(
-		argparse.Description("Sample argument parser"))
+		argparse.Description("Sample argument parser"),
+		argparse.Stderr(io.Discard),
+		argparse.Exit(func(code int) {}))
 
 	count, err := p.AddArgument(
 		argparse.Action("store"),
@@ -83,3 +98,3804 @@ func TestArgparse(t *testing.T) {
 		t.Fatalf("expected %d but got %d", 12345, i)
 	}
 }
+
+func TestAddComputed(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+
+	workers := p.MustAddArgument(
+		argparse.Action("store"),
+		argparse.OptionStrings("-w", "--workers"),
+		argparse.Type(argparse.Int),
+		argparse.Help("Number of workers."))
+
+	_ = p.MustAddArgument(
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.OptionStrings("-v", "--verbose"),
+		argparse.Help("Verbose output."))
+
+	if err := p.AddComputed("workers", func(ns argparse.Namespace) (interface{}, error) {
+		return 4, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var bound int
+	workers.MustBind(&bound)
+
+	p.Finalize(func(ns argparse.Namespace) error {
+		ns.Set(workers, ns.MustGet(workers).(int)*2)
+		return nil
+	})
+
+	if _, err := p.ParseArgs("-w", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if bound != 6 {
+		t.Fatalf("expected %d but got %d", 6, bound)
+	}
+
+	if _, err := p.ParseArgs("-v"); err != nil {
+		t.Fatal(err)
+	}
+	if bound != 8 {
+		t.Fatalf("expected %d but got %d", 8, bound)
+	}
+}
+
+func TestParserNargs(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+
+	verbose := p.MustAddArgument(
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.OptionStrings("-v", "--verbose"))
+
+	command := p.MustAddArgument(
+		argparse.OptionStrings("command"),
+		argparse.Nargs(argparse.Parser))
+
+	ns, err := p.ParseArgs("-v", "build", "-v", "--output", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(verbose); v != true {
+		t.Fatalf("expected verbose but got %v", v)
+	}
+	ss := ns.MustGetStrings(command)
+	expect := []string{"build", "-v", "--output", "x"}
+	if len(ss) != len(expect) {
+		t.Fatalf("expected %v but got %v", expect, ss)
+	}
+	for i, s := range ss {
+		if s != expect[i] {
+			t.Fatalf("expected %v but got %v", expect, ss)
+		}
+	}
+}
+
+func TestZeroOrMoreNargs(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+
+	verbose := p.MustAddArgument(
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.OptionStrings("-v", "--verbose"))
+
+	files := p.MustAddArgument(
+		argparse.OptionStrings("files"),
+		argparse.Nargs(argparse.ZeroOrMore))
+
+	ns, err := p.ParseArgs("a", "b", "-v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(verbose); v != true {
+		t.Fatalf("expected verbose but got %v", v)
+	}
+	ss := ns.MustGetStrings(files)
+	if len(ss) != 2 || ss[0] != "a" || ss[1] != "b" {
+		t.Fatalf("expected [a b] but got %v", ss)
+	}
+	if _, err := p.ParseArgs("-v"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClamp(t *testing.T) {
+	t.Parallel()
+
+	percent := func(v string) (interface{}, error) {
+		x, err := argparse.Int(v)
+		if err != nil {
+			return nil, err
+		}
+		switch i := x.(int); {
+		case i < 0:
+			return nil, &argparse.RangeError{Value: i, Nearest: 0, Range: "[0, 100]"}
+		case i > 100:
+			return nil, &argparse.RangeError{Value: i, Nearest: 100, Range: "[0, 100]"}
+		}
+		return x, nil
+	}
+	var sb strings.Builder
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.Stderr(&sb))
+	volume := p.MustAddArgument(
+		argparse.OptionStrings("--volume"),
+		argparse.Action("store"),
+		argparse.Type(percent),
+		argparse.Clamp)
+	p.MustAddArgument(
+		argparse.OptionStrings("--balance"),
+		argparse.Action("store"),
+		argparse.Type(percent))
+
+	ns, err := p.ParseArgs("--volume", "150")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.MustGet(volume) != 100 {
+		t.Fatalf("expected volume clamped to 100: %v", ns)
+	}
+	if !strings.Contains(sb.String(), "using 100") {
+		t.Fatalf("expected clamping warning, got %q", sb.String())
+	}
+	if _, err := p.ParseArgs("--balance", "150"); err == nil {
+		t.Fatal("expected error from out of range balance")
+	}
+}
+
+func TestPositionalDistribution(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+
+	sources := p.MustAddArgument(
+		argparse.OptionStrings("sources"),
+		argparse.Nargs(argparse.ZeroOrMore))
+
+	target := p.MustAddArgument(
+		argparse.Action("store"),
+		argparse.OptionStrings("target"))
+
+	ns, err := p.ParseArgs("a", "b", "c", "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ss := ns.MustGetStrings(sources); len(ss) != 3 {
+		t.Fatalf("expected 3 sources but got %v", ss)
+	}
+	if v := ns.MustGet(target); v != "target" {
+		t.Fatalf("expected %q but got %v", "target", v)
+	}
+}
+
+func TestTokenizeEvaluate(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+
+	count := p.MustAddArgument(
+		argparse.Action("store"),
+		argparse.OptionStrings("-c", "--count"),
+		argparse.Type(argparse.Int))
+
+	tokens, err := p.Tokenize("--count", "12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens but got %v", tokens)
+	}
+	if tokens[0].Kind != argparse.TokenOption || tokens[0].Argument != count {
+		t.Fatalf("expected option token for %q but got %v", count.Dest, tokens[0])
+	}
+	if tokens[1].Kind != argparse.TokenValue || tokens[1].Index != 1 {
+		t.Fatalf("expected value token at index 1 but got %v", tokens[1])
+	}
+	tokens[1].Value = "34"
+	ns, err := p.Evaluate(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(count); v != 34 {
+		t.Fatalf("expected %d but got %v", 34, v)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+
+	verbose := p.MustAddArgument(
+		argparse.OptionStrings("-v", "--verbose"),
+		argparse.Action("store_true"))
+	all := p.MustAddArgument(
+		argparse.OptionStrings("-a", "--all"),
+		argparse.Action("store_true"))
+	output := p.MustAddArgument(
+		argparse.OptionStrings("-o", "--output"),
+		argparse.Action("store"))
+	files := p.MustAddArgument(
+		argparse.Dest("files"),
+		argparse.Nargs(argparse.ZeroOrMore))
+
+	type tok struct {
+		kind  argparse.TokenKind
+		value string
+		index int
+		arg   *argparse.Argument
+	}
+	tests := []struct {
+		args   []string
+		tokens []tok
+	}{
+		{
+			[]string{"--output", "x", "a"},
+			[]tok{
+				{argparse.TokenOption, "--output", 0, output},
+				{argparse.TokenValue, "x", 1, output},
+				{argparse.TokenPositional, "a", 2, files},
+			},
+		},
+		{
+			[]string{"--output=x", "a"},
+			[]tok{
+				{argparse.TokenOption, "--output", 0, output},
+				{argparse.TokenValue, "x", 0, output},
+				{argparse.TokenPositional, "a", 1, files},
+			},
+		},
+		{
+			[]string{"-va", "a"},
+			[]tok{
+				{argparse.TokenOption, "-v", 0, verbose},
+				{argparse.TokenOption, "-a", 0, all},
+				{argparse.TokenPositional, "a", 1, files},
+			},
+		},
+		{
+			[]string{"-vo", "x"},
+			[]tok{
+				{argparse.TokenOption, "-v", 0, verbose},
+				{argparse.TokenOption, "-o", 0, output},
+				{argparse.TokenValue, "x", 1, output},
+			},
+		},
+		{
+			[]string{"-vox"},
+			[]tok{
+				{argparse.TokenOption, "-v", 0, verbose},
+				{argparse.TokenOption, "-o", 0, output},
+				{argparse.TokenValue, "x", 0, output},
+			},
+		},
+		{
+			[]string{"-v", "--", "-a"},
+			[]tok{
+				{argparse.TokenOption, "-v", 0, verbose},
+				{argparse.TokenTerminator, "--", 1, nil},
+				{argparse.TokenPositional, "-a", 2, files},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tokens, err := p.Tokenize(tc.args...)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.args, err)
+		}
+		if len(tokens) != len(tc.tokens) {
+			t.Fatalf("%v: expected %d tokens but got %v", tc.args, len(tc.tokens), tokens)
+		}
+		for i, want := range tc.tokens {
+			got := tokens[i]
+			if got.Kind != want.kind || got.Value != want.value || got.Index != want.index || got.Argument != want.arg {
+				t.Fatalf("%v: expected %v %q at index %d but got %v", tc.args, want.kind, want.value, want.index, got)
+			}
+		}
+	}
+	ns, err := p.ParseArgs("-va", "--output=y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(all); v != true {
+		t.Fatalf("expected %v but got %v", true, v)
+	}
+	if v := ns.MustGet(output); v != "y" {
+		t.Fatalf("expected %q but got %v", "y", v)
+	}
+	if _, err := p.Tokenize("--verbose=x"); err == nil {
+		t.Fatal("expected error for a value of a flag")
+	}
+}
+
+func TestComplete(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+
+	_ = p.MustAddArgument(
+		argparse.Action("store"),
+		argparse.OptionStrings("-l", "--level"),
+		argparse.ChoiceValues("debug", "info", "warn"))
+
+	_ = p.MustAddArgument(
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.OptionStrings("--lines"))
+
+	_ = p.MustAddArgument(
+		argparse.Action("append"),
+		argparse.OptionStrings("--files"),
+		argparse.Nargs(argparse.OneOrMore),
+		argparse.ChoiceValues("a.go", "b.go"))
+
+	for _, tc := range []struct {
+		words  []string
+		cursor int
+		expect []string
+	}{
+		{[]string{"--l"}, 0, []string{"--level", "--lines"}},
+		{[]string{"--level", "d"}, 1, []string{"debug"}},
+		{[]string{"--lines"}, 1, []string{"-h", "--help", "-l", "--level", "--lines", "--files"}},
+		{[]string{"--files"}, 1, []string{"a.go", "b.go"}},
+		{[]string{"--files", "a.go"}, 2, []string{"a.go", "b.go", "-h", "--help", "-l", "--level", "--lines", "--files"}},
+	} {
+		items, err := p.Complete(tc.words, tc.cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != len(tc.expect) {
+			t.Fatalf("%v: expected %v but got %v", tc.words, tc.expect, items)
+		}
+		for i, item := range items {
+			if item.Value != tc.expect[i] {
+				t.Fatalf("%v: expected %v but got %v", tc.words, tc.expect, items)
+			}
+		}
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+
+	count := p.MustAddArgument(
+		argparse.Action("store"),
+		argparse.OptionStrings("-c", "--count"),
+		argparse.Type(argparse.Int),
+		argparse.Default(1))
+
+	verbose := p.MustAddArgument(
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.OptionStrings("-v", "--verbose"))
+
+	ns, err := p.ParseValues(url.Values{"verbose": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(count); v != 1 {
+		t.Fatalf("expected %d but got %v", 1, v)
+	}
+	if v := ns.MustGet(verbose); v != true {
+		t.Fatalf("expected verbose but got %v", v)
+	}
+
+	if _, err = p.ParseValues(url.Values{"count": {"x"}}); err == nil {
+		t.Fatal("expected error parsing invalid count")
+	}
+	if _, err = p.ParseValues(url.Values{"size": {"1"}}); err == nil {
+		t.Fatal("expected error for unexpected parameter")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		answer string
+		ok     bool
+	}{
+		{"y\n", true},
+		{"no\n", false},
+	} {
+		p := argparse.MustNewArgumentParser(
+			argparse.Stdin(strings.NewReader(tc.answer)),
+			argparse.Stderr(io.Discard))
+
+		force := p.MustAddArgument(
+			argparse.ActionFunc(argparse.StoreTrue),
+			argparse.OptionStrings("--force"),
+			argparse.Confirm("This will delete data, continue?"))
+
+		_ = p.MustAddArgument(
+			argparse.Action("store"),
+			argparse.OptionStrings("target"))
+
+		ns, err := p.ParseArgs("data")
+		if !tc.ok {
+			if err == nil {
+				t.Fatalf("%q: expected error", tc.answer)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := ns.MustGet(force); v != true {
+			t.Fatalf("%q: expected force but got %v", tc.answer, v)
+		}
+	}
+}
+
+func TestBooleanOptional(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+
+	color := p.MustAddArgument(
+		argparse.ActionFunc(argparse.BooleanOptional),
+		argparse.OptionStrings("--color"),
+		argparse.Default(true))
+
+	for _, tc := range []struct {
+		args   []string
+		expect bool
+	}{
+		{[]string{"--no-color"}, false},
+		{[]string{"--no-color", "--color"}, true},
+	} {
+		ns, err := p.ParseArgs(tc.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := ns.MustGet(color); v != tc.expect {
+			t.Fatalf("%v: expected %v but got %v", tc.args, tc.expect, v)
+		}
+	}
+}
+
+func TestArgumentGroup(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	g := p.AddArgumentGroup("connection options", "how to reach the server")
+
+	host := g.MustAddArgument(
+		argparse.OptionStrings("--host"),
+		argparse.Action("store"),
+		argparse.Help("server host name"))
+
+	ns, err := p.ParseArgs("--host", "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(host); v != "example.com" {
+		t.Fatalf("expected %q but got %v", "example.com", v)
+	}
+
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := strings.Index(help, "connection options:\n  how to reach the server\n")
+	if i == -1 {
+		t.Fatalf("group header not found in help:\n%s", help)
+	}
+	if j := strings.Index(help, "  --host HOST"); j < i {
+		t.Fatalf("expected --host under the group header:\n%s", help)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Strict)
+
+	if _, err := p.AddArgument(
+		argparse.OptionStrings("--name"),
+		argparse.Action("store"),
+	); err == nil {
+		t.Fatal("expected error adding argument without a Type")
+	}
+
+	count := p.MustAddArgument(
+		argparse.OptionStrings("--count"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+
+	var s string
+	if err := count.Bind(&s); err == nil {
+		t.Fatal("expected error binding int argument to string")
+	}
+	var n int64
+	if err := count.Bind(&n); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAutoIndent(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.AutoIndent)
+	p.MustAddArgument(
+		argparse.OptionStrings("-v"),
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.Help("verbose output"))
+
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"  -h, --help  show this help message and exit\n",
+		"  -v          verbose output\n",
+	} {
+		if !strings.Contains(help, line) {
+			t.Fatalf("expected %q in help:\n%s", line, help)
+		}
+	}
+}
+
+func TestParents(t *testing.T) {
+	t.Parallel()
+
+	parent := argparse.MustNewArgumentParser(argparse.NoHelp)
+	parent.MustAddArgument(
+		argparse.OptionStrings("-v", "--verbose"),
+		argparse.ActionFunc(argparse.StoreTrue))
+
+	child := argparse.MustNewArgumentParser(argparse.Parents(parent))
+	ns, err := child.ParseArgs("--verbose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := ns["verbose"]; !ok || v != true {
+		t.Fatalf("expected verbose to be true, not %v", v)
+	}
+
+	if _, err := child.AddArgument(
+		argparse.OptionStrings("-v"),
+		argparse.ActionFunc(argparse.StoreTrue),
+	); err == nil {
+		t.Fatal("expected error redefining parent's option")
+	}
+	if _, err := argparse.NewArgumentParser(
+		argparse.Parents(parent, parent),
+	); err == nil {
+		t.Fatal("expected error from conflicting parents")
+	}
+}
+
+func TestConflictResolve(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.ConflictHandler(argparse.ConflictResolve))
+	p.MustAddArgument(
+		argparse.OptionStrings("-f", "--foo"),
+		argparse.ActionFunc(argparse.StoreTrue))
+	p.MustAddArgument(
+		argparse.OptionStrings("-f", "--force"),
+		argparse.ActionFunc(argparse.StoreTrue))
+
+	ns, err := p.ParseArgs("-f", "--foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns["force"] != true || ns["foo"] != true {
+		t.Fatalf("expected -f to set force: %v", ns)
+	}
+
+	p.MustAddArgument(
+		argparse.OptionStrings("--foo"),
+		argparse.Dest("bar"),
+		argparse.ActionFunc(argparse.StoreTrue))
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(help, "--foo") != 2 {
+		t.Fatalf("expected the replaced argument to be removed:\n%s", help)
+	}
+}
+
+func TestHelpTheme(t *testing.T) {
+	t.Parallel()
+
+	var sb strings.Builder
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.HelpTheme(argparse.ThemeLight),
+		argparse.Stderr(&sb),
+		argparse.Exit(func(code int) {}))
+	if _, err := p.ParseArgs("-h"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), "\x1b[34m--help\x1b[0m") {
+		t.Fatalf("expected colored option strings in:\n%q", sb.String())
+	}
+}
+
+func TestQuotedCommandLine(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	p.MustAddArgument(
+		argparse.OptionStrings("-m", "--message"),
+		argparse.Action("store"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--password"),
+		argparse.Action("store"),
+		argparse.Secret)
+	p.MustAddArgument(
+		argparse.OptionStrings("-v"),
+		argparse.ActionFunc(argparse.StoreTrue))
+	p.MustAddArgument(
+		argparse.Dest("files"),
+		argparse.Nargs(argparse.ZeroOrMore))
+
+	ns, err := p.ParseArgs(
+		"-m", "it's done", "--password", "hunter2", "-v",
+		"--", "a b", "-c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `test --message 'it'\''s done' --password REDACTED -v -- 'a b' -c`
+	if v := ns.QuotedCommandLine(p); v != expect {
+		t.Fatalf("expected:\n\t%s\nactual:\n\t%s", expect, v)
+	}
+}
+
+func TestRequiresConflictsWith(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	cert := p.MustAddArgument(
+		argparse.OptionStrings("--cert-file"),
+		argparse.Action("store"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--key-file"),
+		argparse.Action("store"),
+		argparse.Requires(cert))
+	quiet := p.MustAddArgument(
+		argparse.OptionStrings("-q", "--quiet"),
+		argparse.ActionFunc(argparse.StoreTrue))
+	p.MustAddArgument(
+		argparse.OptionStrings("--json"),
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.ConflictsWith(quiet))
+
+	for _, tc := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"--key-file", "k"}, false},
+		{[]string{"--key-file", "k", "--cert-file", "c"}, true},
+		{[]string{"--json"}, true},
+		{[]string{"--json", "-q"}, false},
+	} {
+		_, err := p.ParseArgs(tc.args...)
+		if (err == nil) != tc.ok {
+			t.Fatalf("%v: unexpected error result: %v", tc.args, err)
+		}
+	}
+}
+
+func TestRequiredIf(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	auth := p.MustAddArgument(
+		argparse.OptionStrings("--auth"),
+		argparse.Action("store"),
+		argparse.Default("none"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--password"),
+		argparse.Action("store"),
+		argparse.RequiredIf(func(ns argparse.Namespace) bool {
+			return ns.MustGet(auth) == "basic"
+		}))
+
+	for _, tc := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"--auth", "none"}, true},
+		{[]string{"--auth", "basic"}, false},
+		{[]string{"--auth", "basic", "--password", "x"}, true},
+	} {
+		_, err := p.ParseArgs(tc.args...)
+		if (err == nil) != tc.ok {
+			t.Fatalf("%v: unexpected error result: %v", tc.args, err)
+		}
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+	parse := argparse.RelativeTimeClock(func() time.Time { return now })
+	for _, tc := range []struct {
+		v      string
+		expect time.Time
+	}{
+		{"now", now},
+		{"now-2h", now.Add(-2 * time.Hour)},
+		{"yesterday", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{"today+1w", time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"2024-01-01", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		v, err := parse(tc.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !v.(time.Time).Equal(tc.expect) {
+			t.Fatalf("%q: expected %v but got %v", tc.v, tc.expect, v)
+		}
+	}
+	if _, err := parse("last tuesday"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGeoCoordinate(t *testing.T) {
+	t.Parallel()
+
+	v, err := argparse.GeoCoordinate("51.5, -0.12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := (argparse.LatLon{Lat: 51.5, Lon: -0.12}); v != expect {
+		t.Fatalf("expected %v but got %v", expect, v)
+	}
+	for _, bad := range []string{"91,0", "0,181", "1", "a,b", "NaN,0", "0,NaN", "+Inf,0", "0,-Inf"} {
+		if _, err := argparse.GeoCoordinate(bad); err == nil {
+			t.Fatalf("expected error from %q", bad)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--include"),
+		argparse.Action("append"),
+		argparse.Nargs(1),
+		argparse.MinOccurrences(1),
+		argparse.MaxOccurrences(2))
+
+	for _, tc := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"--include", "a"}, true},
+		{[]string{"--include", "a", "--include", "b"}, true},
+		{[]string{"--include", "a", "--include", "b", "--include", "c"}, false},
+		{[]string{"--"}, false},
+	} {
+		_, err := p.ParseArgs(tc.args...)
+		if (err == nil) != tc.ok {
+			t.Fatalf("%v: unexpected error result: %v", tc.args, err)
+		}
+	}
+}
+
+func TestStoreMap(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	port := p.MustAddArgument(
+		argparse.OptionStrings("--port"),
+		argparse.ActionFunc(argparse.StoreMap),
+		argparse.Type(argparse.Int))
+
+	var ports map[string]int
+	port.MustBind(&ports)
+	if _, err := p.ParseArgs("--port", "http=80", "--port", "https=443"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ports) != 2 || ports["http"] != 80 || ports["https"] != 443 {
+		t.Fatalf("unexpected ports: %v", ports)
+	}
+	if _, err := p.ParseArgs("--port", "http"); err == nil {
+		t.Fatal("expected error from value without a key")
+	}
+}
+
+func TestDigest(t *testing.T) {
+	t.Parallel()
+
+	const empty = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	v, err := argparse.Digest(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := v.(argparse.Checksum); c.Algorithm != "sha256" || c.String() != empty {
+		t.Fatalf("unexpected checksum: %v", c)
+	}
+	for _, bad := range []string{"sha256:abcd", "crc32:00000000", "e3b0c442", "md5:zz"} {
+		if _, err := argparse.Digest(bad); err == nil {
+			t.Fatalf("expected error from %q", bad)
+		}
+	}
+}
+
+func TestSubparsers(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	p.MustAddArgument(
+		argparse.OptionStrings("-v"),
+		argparse.ActionFunc(argparse.StoreTrue))
+	ss := p.MustAddSubparsers(argparse.Dest("command"))
+	add := ss.MustAddParser("add")
+	add.MustAddArgument(argparse.Dest("url"), argparse.Nargs(1))
+	add.MustAddArgument(
+		argparse.OptionStrings("-f"),
+		argparse.ActionFunc(argparse.StoreTrue))
+	ss.MustAddParser("list")
+
+	ns, err := p.ParseArgs("-v", "add", "-f", "http://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns["command"] != "add" || ns["url"] != "http://example.com" ||
+		ns["v"] != true || ns["f"] != true {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+	if add.Prog != "tool add" {
+		t.Fatalf("unexpected sub-parser prog: %q", add.Prog)
+	}
+	if _, err := p.ParseArgs("remove"); err == nil {
+		t.Fatal("expected error from unknown sub-command")
+	}
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(help, "{add,list}") {
+		t.Fatalf("expected sub-commands in help:\n%s", help)
+	}
+}
+
+func TestLanguageTag(t *testing.T) {
+	t.Parallel()
+
+	for v, expect := range map[string]string{
+		"en":             "en",
+		"en_us":          "en-US",
+		"ZH-hant-tw":     "zh-Hant-TW",
+		"es-419":         "es-419",
+		"de-CH-1996":     "de-CH-1996",
+		"en-a-bbb-x-a-b": "en-a-bbb-x-a-b",
+	} {
+		v2, err := argparse.LanguageTag(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v2 != expect {
+			t.Fatalf("%q: expected %q but got %q", v, expect, v2)
+		}
+	}
+	for _, bad := range []string{"", "e", "english-", "en-US-x", "en-a-b"} {
+		if _, err := argparse.LanguageTag(bad); err == nil {
+			t.Fatalf("expected error from %q", bad)
+		}
+	}
+}
+
+func TestSubcommandAliases(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	ss := p.MustAddSubparsers(argparse.Dest("command"))
+	ss.MustAddParser(
+		"remove",
+		argparse.Aliases("rm", "del"),
+		argparse.Description("remove an item"))
+
+	ns, err := p.ParseArgs("rm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns["command"] != "remove" {
+		t.Fatalf("unexpected command: %v", ns["command"])
+	}
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(help, "remove (rm, del)") {
+		t.Fatalf("expected aliases in help:\n%s", help)
+	}
+	if _, err := ss.AddParser("delete", argparse.Aliases("rm")); err == nil {
+		t.Fatal("expected error redefining alias")
+	}
+}
+
+func TestFormats(t *testing.T) {
+	t.Parallel()
+
+	if v, err := argparse.MIMEType("Application/JSON"); err != nil || v != "application/json" {
+		t.Fatalf("unexpected MIME type %v: %v", v, err)
+	}
+	if _, err := argparse.MIMEType("json"); err == nil {
+		t.Fatal("expected error from MIME type without a subtype")
+	}
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	format := p.MustAddArgument(
+		argparse.OptionStrings("--format"),
+		argparse.Action("store"),
+		argparse.FileExtensions("csv", ".json"))
+	ns, err := p.ParseArgs("--format", "CSV")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(format); v != ".csv" {
+		t.Fatalf("expected .csv but got %v", v)
+	}
+	if _, err := p.ParseArgs("--format", "xml"); err == nil {
+		t.Fatal("expected error from unlisted extension")
+	}
+	items, err := p.Complete([]string{"--format", ".j"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Value != ".json" {
+		t.Fatalf("unexpected completions: %v", items)
+	}
+}
+
+func TestRequiredSubcommand(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	ss := p.MustAddSubparsers(argparse.Required)
+	ss.MustAddParser("serve")
+	if _, err := p.ParseArgs("--"); err == nil {
+		t.Fatal("expected error from missing sub-command")
+	}
+	if _, err := p.ParseArgs("serve"); err != nil {
+		t.Fatal(err)
+	}
+
+	p = argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	ss = p.MustAddSubparsers(argparse.Dest("command"))
+	ss.MustAddParser("serve")
+	ns, err := p.ParseArgs("--")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ns["command"]; ok {
+		t.Fatalf("unexpected command: %v", ns)
+	}
+}
+
+func TestNestedSubcommands(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	remote := p.MustAddSubparsers(argparse.Dest("command")).
+		MustAddParser("remote")
+	add := remote.MustAddSubparsers(argparse.Dest("remote_command")).
+		MustAddParser("add")
+	add.MustAddArgument(argparse.Dest("url"), argparse.Nargs(1))
+
+	ns, err := p.ParseArgs("remote", "add", "https://example.com/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns["command"] != "remote" || ns["remote_command"] != "add" ||
+		ns["url"] != "https://example.com/repo" {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+	help, err := add.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(help, "usage: tool remote add ") {
+		t.Fatalf("expected composed prog in usage:\n%s", help)
+	}
+}
+
+func TestRate(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		v      string
+		expect argparse.Throughput
+	}{
+		{"100/s", argparse.Throughput{Amount: 100, Per: time.Second}},
+		{"5k/min", argparse.Throughput{Amount: 5000, Per: time.Minute}},
+		{"1.5MB/s", argparse.Throughput{Amount: 1.5e6, Bytes: true, Per: time.Second}},
+		{"2KiB/5m", argparse.Throughput{Amount: 2048, Bytes: true, Per: 5 * time.Minute}},
+		{"10/ms", argparse.Throughput{Amount: 10, Per: time.Millisecond}},
+		{"10/s", argparse.Throughput{Amount: 10, Per: time.Second}},
+		{"10/secs", argparse.Throughput{Amount: 10, Per: time.Second}},
+		{"10/hours", argparse.Throughput{Amount: 10, Per: time.Hour}},
+	} {
+		v, err := argparse.Rate(tc.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.expect {
+			t.Fatalf("%q: expected %v but got %v", tc.v, tc.expect, v)
+		}
+	}
+	for _, bad := range []string{"100", "fast/s", "1/fortnight", "-1/s"} {
+		if _, err := argparse.Rate(bad); err == nil {
+			t.Fatalf("expected error from %q", bad)
+		}
+	}
+}
+
+func TestDefaultSubcommand(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	ss := p.MustAddSubparsers(
+		argparse.Dest("command"),
+		argparse.Default("serve"))
+	serve := ss.MustAddParser("serve")
+	serve.MustAddArgument(
+		argparse.OptionStrings("--port"),
+		argparse.Action("store"),
+		argparse.Default("8080"))
+	ss.MustAddParser("migrate")
+
+	ns, err := p.ParseArgs("--")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns["command"] != "serve" || ns["port"] != "8080" {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+	ns, err = p.ParseArgs("migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns["command"] != "migrate" {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	var ran string
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	remote := p.MustAddSubparsers().MustAddParser("remote")
+	remote.MustAddSubparsers().MustAddParser(
+		"add",
+		argparse.Run(func(ns argparse.Namespace) error {
+			ran = "add " + ns["url"].(string)
+			return nil
+		}),
+	).MustAddArgument(argparse.Dest("url"), argparse.Nargs(1))
+
+	if err := p.Execute("remote", "add", "u"); err != nil {
+		t.Fatal(err)
+	}
+	if ran != "add u" {
+		t.Fatalf("unexpected handler result: %q", ran)
+	}
+	if err := p.Execute("remote"); err == nil {
+		t.Fatal("expected error from command without a handler")
+	}
+}
+
+func TestGoTemplate(t *testing.T) {
+	t.Parallel()
+
+	v, err := argparse.GoTemplate("{{.Name}}!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := v.(*template.Template).Execute(&sb, struct{ Name string }{"x"}); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != "x!" {
+		t.Fatalf("unexpected output: %q", sb.String())
+	}
+	if _, err := argparse.GoTemplate("{{.Name"); err == nil {
+		t.Fatal("expected error from unclosed action")
+	}
+	if _, err := argparse.GoTemplate("{{upper .Name}}"); err == nil {
+		t.Fatal("expected error from undefined function")
+	}
+	parse := argparse.GoTemplateFuncs(template.FuncMap{"upper": strings.ToUpper})
+	if _, err := parse("{{upper .Name}}"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFields(t *testing.T) {
+	t.Parallel()
+
+	v, err := argparse.Fields("1,3-5,name,7-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := v.(argparse.FieldSelection)
+	if fs.String() != "1,3-5,name,7-" {
+		t.Fatalf("unexpected selection: %v", fs)
+	}
+	for _, tc := range []struct {
+		number int
+		name   string
+		expect bool
+	}{
+		{1, "", true}, {2, "", false}, {4, "", true},
+		{6, "name", true}, {6, "", false}, {100, "", true},
+	} {
+		if fs.Selected(tc.number, tc.name) != tc.expect {
+			t.Fatalf("%d %q: expected %v", tc.number, tc.name, tc.expect)
+		}
+	}
+	parse := argparse.FieldsOf("pid", "user", "cmd")
+	if _, err := parse("pid,2-3"); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"pid,4", "mem", "5-3", ""} {
+		if _, err := parse(bad); err == nil {
+			t.Fatalf("expected error from %q", bad)
+		}
+	}
+}
+
+func TestPersistent(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	p.MustAddArgument(
+		argparse.OptionStrings("-v", "--verbose"),
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.Persistent)
+	p.MustAddArgument(
+		argparse.OptionStrings("-q"),
+		argparse.ActionFunc(argparse.StoreTrue))
+	build := p.MustAddSubparsers().MustAddParser("build")
+
+	for _, args := range [][]string{
+		{"--verbose", "build"},
+		{"build", "--verbose"},
+	} {
+		ns, err := p.ParseArgs(args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ns["verbose"] != true {
+			t.Fatalf("%v: expected verbose: %v", args, ns)
+		}
+	}
+	if _, err := p.ParseArgs("build", "-q"); err == nil {
+		t.Fatal("expected error from non-persistent option")
+	}
+	help, err := build.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(help, "global options:\n  -v, --verbose") {
+		t.Fatalf("expected global options in help:\n%s", help)
+	}
+}
+
+func TestSubcommandSuggestion(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	ss := p.MustAddSubparsers()
+	ss.MustAddParser("build")
+	ss.MustAddParser("serve")
+
+	_, err := p.ParseArgs("biuld")
+	if err == nil || !strings.Contains(err.Error(), `did you mean "build"?`) {
+		t.Fatalf("expected suggestion in error: %v", err)
+	}
+	_, err = p.ParseArgs("xyzzy")
+	if err == nil || strings.Contains(err.Error(), "did you mean") {
+		t.Fatalf("expected error without suggestion: %v", err)
+	}
+}
+
+func TestStringSpec(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--name"),
+		argparse.Action("store"),
+		argparse.StringSpecType(2, 8, "[a-z][a-z0-9-]*"))
+
+	if _, err := p.ParseArgs("--name", "web-1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"w", "web-server-1", "Web"} {
+		if _, err := p.ParseArgs("--name", bad); err == nil {
+			t.Fatalf("expected error from %q", bad)
+		}
+	}
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(help, "(2-8 characters matching [a-z][a-z0-9-]*)") {
+		t.Fatalf("expected restrictions in help:\n%s", help)
+	}
+	if _, err := argparse.StringSpec(0, 0, "(")("x"); err == nil {
+		t.Fatal("expected error from invalid pattern")
+	}
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	var stats argparse.ParseStats
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.Stats(func(s argparse.ParseStats) { stats = s }))
+	p.MustAddArgument(
+		argparse.OptionStrings("-n"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+	p.MustAddArgument(argparse.Dest("files"), argparse.Nargs(argparse.OneOrMore))
+
+	if _, err := p.ParseArgs("-n", "1", "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Tokens != 4 || stats.Options != 1 || stats.Values != 3 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if _, ok := stats.ArgumentTimes["n"]; !ok {
+		t.Fatalf("expected time of argument n: %+v", stats)
+	}
+}
+
+func TestCommandsHelp(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	ss := p.MustAddSubparsers(argparse.Default("serve"))
+	ss.MustAddParser("serve", argparse.Description("run the server\n\nmore details"))
+	ss.MustAddParser("migrate", argparse.Aliases("m"), argparse.Description("migrate the database"))
+
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"  {serve,migrate}\n",
+		"commands:\n",
+		"  serve         run the server (default)\n",
+		"  migrate (m)   migrate the database\n",
+	} {
+		if !strings.Contains(help, line) {
+			t.Fatalf("expected %q in help:\n%s", line, help)
+		}
+	}
+}
+
+func BenchmarkParseArgs(b *testing.B) {
+	p := argparse.MustNewArgumentParser(argparse.Prog("bench"))
+	p.MustAddArgument(
+		argparse.OptionStrings("-n", "--count"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+	p.MustAddArgument(
+		argparse.OptionStrings("-v", "--verbose"),
+		argparse.ActionFunc(argparse.StoreTrue))
+	p.MustAddArgument(
+		argparse.OptionStrings("--label"),
+		argparse.ActionFunc(argparse.StoreMap))
+	p.MustAddArgument(argparse.Dest("files"), argparse.Nargs(argparse.OneOrMore))
+	args := []string{"-v", "--count", "3", "--label", "a=b", "x", "y", "z"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.ParseArgs(args...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseArgsParallel(b *testing.B) {
+	p := argparse.MustNewArgumentParser(argparse.Prog("bench"))
+	p.MustAddArgument(
+		argparse.OptionStrings("-n", "--count"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+	p.MustAddArgument(argparse.Dest("files"), argparse.Nargs(argparse.OneOrMore))
+	args := []string{"--count", "3", "x", "y", "z"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := p.ParseArgs(args...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestCompile(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	n := p.MustAddArgument(
+		argparse.OptionStrings("-n"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+	c := p.MustCompile()
+	p.MustAddArgument(
+		argparse.OptionStrings("-x"),
+		argparse.ActionFunc(argparse.StoreTrue))
+
+	done := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			ns, err := c.ParseArgs("-n", "5")
+			if err == nil && ns.MustGet(n) != 5 {
+				err = errors.Errorf("unexpected namespace: %v", ns)
+			}
+			done <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.ParseArgs("-x"); err == nil {
+		t.Fatal("expected -x added after compiling to be unknown")
+	}
+	help, err := c.FormatHelp(80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(help, "-x") {
+		t.Fatalf("unexpected -x in compiled help:\n%s", help)
+	}
+
+	var v int
+	n.MustBind(&v)
+	if _, err := p.Compile(); err == nil {
+		t.Fatal("expected error compiling a parser with bound arguments")
+	}
+
+	p = argparse.MustNewArgumentParser(argparse.Prog("test"))
+	build := p.MustAddSubparsers().MustAddParser("build")
+	build.MustAddArgument(
+		argparse.OptionStrings("-j"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int)).MustBind(&v)
+	if _, err := p.Compile(); err == nil {
+		t.Fatal("expected error compiling a parser with a " +
+			"sub-command with bound arguments")
+	}
+}
+
+func TestDeprecation(t *testing.T) {
+	t.Parallel()
+
+	newParser := func(sb *strings.Builder, strict bool, version string) *argparse.ArgumentParser {
+		opts := []argparse.ArgumentParserOption{
+			argparse.Prog("test"),
+			argparse.Version(version),
+			argparse.Stderr(sb),
+		}
+		if strict {
+			opts = append(opts, argparse.StrictDeprecation)
+		}
+		p := argparse.MustNewArgumentParser(opts...)
+		p.MustAddArgument(
+			argparse.OptionStrings("--old"),
+			argparse.ActionFunc(argparse.StoreTrue),
+			argparse.Deprecated("use --new instead"),
+			argparse.RemovedIn("v2.0"))
+		return p
+	}
+
+	var sb strings.Builder
+	p := newParser(&sb, true, "v1.9")
+	if _, err := p.ParseArgs("--old"); err != nil {
+		t.Fatal(err)
+	}
+	const warning = "test: warning: --old is deprecated; removed in v2.0: use --new instead\n"
+	if sb.String() != warning {
+		t.Fatalf("unexpected warning: %q", sb.String())
+	}
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(help, "(deprecated; removed in v2.0)") {
+		t.Fatalf("expected deprecation note in help:\n%s", help)
+	}
+
+	sb.Reset()
+	p = newParser(&sb, false, "2")
+	if _, err := p.ParseArgs("--old"); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != warning {
+		t.Fatalf("unexpected warning: %q", sb.String())
+	}
+	p = newParser(&sb, true, "2")
+	if _, err := p.ParseArgs("--old"); err == nil {
+		t.Fatal("expected an error using a removed argument in strict mode")
+	}
+
+	var d struct {
+		Arguments []struct {
+			Dest      string `json:"dest"`
+			RemovedIn string `json:"removed_in"`
+		} `json:"arguments"`
+	}
+	b, err := p.DescribeJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, a := range d.Arguments {
+		if a.Dest == "old" {
+			found = a.RemovedIn == "v2.0"
+		}
+	}
+	if !found {
+		t.Fatalf("expected removed_in of --old in %s", b)
+	}
+}
+
+func TestDescribeJSON(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("tool"),
+		argparse.Version("1.0"))
+	p.MustAddArgument(
+		argparse.OptionStrings("-l", "--level"),
+		argparse.Action("store"),
+		argparse.ChoiceValues("debug", "info"),
+		argparse.Help("log level"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--internal"),
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.Help(argparse.Suppress))
+	ss := p.MustAddSubparsers(argparse.Dest("command"))
+	build := ss.MustAddParser("build")
+	build.MustAddArgument(
+		argparse.Dest("targets"),
+		argparse.Nargs(argparse.OneOrMore))
+
+	b, err := p.DescribeJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d map[string]interface{}
+	if err := json.Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	for _, expect := range []string{
+		`"prog":"tool"`,
+		`"version":"1.0"`,
+		`"option_strings":["-l","--level"]`,
+		`"choices":["debug","info"]`,
+		`"help":"log level"`,
+		`"name":"build"`,
+		`"prog":"tool build"`,
+		`"dest":"targets","nargs":"+"`,
+	} {
+		if !strings.Contains(s, expect) {
+			t.Fatalf("expected %s in %s", expect, s)
+		}
+	}
+	if strings.Contains(s, "--internal") {
+		t.Fatalf("expected suppressed argument to be left out of %s", s)
+	}
+}
+
+func TestRenameArgument(t *testing.T) {
+	t.Parallel()
+
+	var sb strings.Builder
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.Stderr(&sb))
+	out := p.MustAddArgument(
+		argparse.OptionStrings("-o", "--output"),
+		argparse.Action("store"),
+		argparse.Dest("output"))
+	p.MustRenameArgument("--out-file", "output")
+
+	ns, err := p.ParseArgs("--out-file", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(out); v != "a.txt" {
+		t.Fatalf("expected %q, got %v", "a.txt", v)
+	}
+	const warning = "test: warning: --out-file is deprecated: use --output instead\n"
+	if sb.String() != warning {
+		t.Fatalf("unexpected warning: %q", sb.String())
+	}
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(help, "--out-file") {
+		t.Fatalf("unexpected renamed option in help:\n%s", help)
+	}
+	if err := p.RenameArgument("--output", "output"); err == nil {
+		t.Fatal("expected error renaming a defined option")
+	}
+	if err := p.RenameArgument("--in", "input"); err == nil {
+		t.Fatal("expected error renaming to an undefined Dest")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	trace := func(name string) argparse.Middleware {
+		return func(next argparse.RunFunc) argparse.RunFunc {
+			return func(ns argparse.Namespace) error {
+				calls = append(calls, name)
+				return next(ns)
+			}
+		}
+	}
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	p.Use(trace("log"), trace("config"))
+	build := p.MustAddSubparsers().MustAddParser(
+		"build",
+		argparse.Run(func(ns argparse.Namespace) error {
+			calls = append(calls, "build")
+			return nil
+		}))
+	build.Use(trace("profile"))
+
+	if err := p.Execute("build"); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(calls, " "); s != "log config profile build" {
+		t.Fatalf("unexpected calls: %q", s)
+	}
+}
+
+func TestTestMode(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.NewTestParser(t, argparse.Version("v1.0"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--version"),
+		argparse.ActionFunc(argparse.ShowVersion))
+	p.MustAddArgument(
+		argparse.OptionStrings("--token"),
+		argparse.Action("store"),
+		argparse.EnvVar("PATH"))
+	sub := p.MustAddSubparsers(argparse.Dest("command"))
+	sub.MustAddParser("run")
+
+	ns, err := p.ParseArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ns["token"]; ok {
+		t.Fatalf("expected environment not to be read: %v", ns)
+	}
+	if _, err := p.ParseArgs("--version"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ParseArgs("run", "-h"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.TestCapture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Stdout != "v1.0\n" || !c.Exited || c.ExitCode != 0 {
+		t.Fatalf("unexpected capture: %+v", c)
+	}
+	if !strings.HasPrefix(c.Stderr, "usage: test run") {
+		t.Fatalf("expected sub-command help in captured stderr: %q", c.Stderr)
+	}
+}
+
+func TestIPAndCIDR(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	addr := p.MustAddArgument(
+		argparse.OptionStrings("--addr"),
+		argparse.Action("store"),
+		argparse.Type(argparse.IP))
+	network := p.MustAddArgument(
+		argparse.OptionStrings("--net"),
+		argparse.Action("store"),
+		argparse.Type(argparse.CIDR))
+
+	ns, err := p.ParseArgs("--addr", "::1", "--net", "10.1.2.3/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := ns.MustGet(addr).(net.IP); !ip.Equal(net.IPv6loopback) {
+		t.Fatalf("unexpected IP: %v", ip)
+	}
+	if n := ns.MustGet(network).(*net.IPNet); n.String() != "10.0.0.0/8" {
+		t.Fatalf("unexpected network: %v", n)
+	}
+	_, err = p.ParseArgs("--addr", "10.0.0.256")
+	if err == nil || !strings.Contains(err.Error(), `argument "addr"`) {
+		t.Fatalf("expected error naming the argument, got %v", err)
+	}
+}
+
+func TestURL(t *testing.T) {
+	t.Parallel()
+
+	parse := argparse.URL("HTTPS", "http")
+	v, err := parse("https://example.com/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := v.(*url.URL); u.Host != "example.com" || u.Path != "/api" {
+		t.Fatalf("unexpected URL: %v", u)
+	}
+	for _, s := range []string{"ftp://example.com", "example.com", "http://%zz"} {
+		if _, err := parse(s); err == nil {
+			t.Fatalf("expected error parsing %q", s)
+		}
+	}
+	if _, err := argparse.URL()("ftp://example.com"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		s string
+		v int64
+	}{
+		{"512", 512},
+		{"10K", 10000},
+		{"1.5GB", 1500000000},
+		{"64MiB", 64 << 20},
+		{"1.5KiB", 1536},
+	} {
+		v, err := argparse.ByteSize(tc.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.v {
+			t.Fatalf("expected %q to be %d, got %v", tc.s, tc.v, v)
+		}
+	}
+	for _, s := range []string{"", "-1K", "1.5", "10X", "1e30"} {
+		if _, err := argparse.ByteSize(s); err == nil {
+			t.Fatalf("expected error parsing %q", s)
+		}
+	}
+}
+
+func TestUUID(t *testing.T) {
+	t.Parallel()
+
+	const canonical = "123e4567-e89b-12d3-a456-426614174000"
+	for _, s := range []string{
+		canonical,
+		"123E4567-E89B-12D3-A456-426614174000",
+		"123e4567e89b12d3a456426614174000",
+	} {
+		v, err := argparse.UUID(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != canonical {
+			t.Fatalf("expected %q to be %q, got %v", s, canonical, v)
+		}
+	}
+	v, err := argparse.UUIDBytes(canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := v.([16]byte); b[0] != 0x12 || b[15] != 0x00 || b[6] != 0x12 {
+		t.Fatalf("unexpected bytes: %x", b)
+	}
+	for _, s := range []string{"", "123e4567-e89b-12d3-a456", "123e4567e-89b-12d3-a456-426614174000", "zz3e4567e89b12d3a456426614174000"} {
+		if _, err := argparse.UUID(s); err == nil {
+			t.Fatalf("expected error parsing %q", s)
+		}
+	}
+}
+
+func TestHexAndBase64Bytes(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		parse argparse.ValueParser
+		s     string
+	}{
+		{argparse.HexBytes, "0x00ff10"},
+		{argparse.HexBytes, "00FF10"},
+		{argparse.Base64Bytes, "AP8Q"},
+		{argparse.Base64Bytes, "AP8QAA=="},
+		{argparse.Base64Bytes, "AP8QAA"},
+	} {
+		v, err := tc.parse(tc.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := v.([]byte); len(b) < 3 || b[0] != 0 || b[1] != 0xff || b[2] != 0x10 {
+			t.Fatalf("unexpected bytes from %q: %x", tc.s, b)
+		}
+	}
+	if _, err := argparse.HexBytes("abc"); err == nil {
+		t.Fatal("expected error from odd number of hex digits")
+	}
+	if _, err := argparse.Base64Bytes("A*8Q"); err == nil {
+		t.Fatal("expected error from invalid base64")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+
+	v, err := argparse.JSON(`{"name":"x","n":[1,2]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := v.(map[string]interface{}); m["name"] != "x" || len(m["n"].([]interface{})) != 2 {
+		t.Fatalf("unexpected value: %v", v)
+	}
+	type filter struct {
+		Name string `json:"name"`
+	}
+	v, err = argparse.JSONInto(filter{})(`{"name":"x"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := v.(filter); f.Name != "x" {
+		t.Fatalf("unexpected value: %v", v)
+	}
+	v, err = argparse.JSONInto((*filter)(nil))(`{"name":"y"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := v.(*filter); f.Name != "y" {
+		t.Fatalf("unexpected value: %v", v)
+	}
+	if _, err := argparse.JSON(`{"name":`); err == nil {
+		t.Fatal("expected error from truncated JSON")
+	}
+	if _, err := argparse.JSONInto(filter{})(`[1]`); err == nil {
+		t.Fatal("expected error unmarshaling an array into a struct")
+	}
+}
+
+func TestFileType(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "out.txt")
+	v, err := argparse.FileType(os.O_CREATE|os.O_WRONLY, 0o644)(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := v.(*os.File)
+	if _, err := f.WriteString("x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	v, err = argparse.FileType(os.O_RDONLY, 0)(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.(*os.File).Close()
+	if v, _ := argparse.FileType(os.O_RDONLY, 0)("-"); v != os.Stdin {
+		t.Fatalf("expected - to be stdin, got %v", v)
+	}
+	if v, _ := argparse.FileType(os.O_WRONLY, 0)("-"); v != os.Stdout {
+		t.Fatalf("expected - to be stdout, got %v", v)
+	}
+	if _, err := argparse.FileType(os.O_RDONLY, 0)(name + ".missing"); err == nil {
+		t.Fatal("expected error opening a missing file")
+	}
+}
+
+func TestPathValidators(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+	readOnly := filepath.Join(dir, "ro")
+	if err := os.Mkdir(readOnly, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		parse argparse.ValueParser
+		path  string
+		ok    bool
+	}{
+		{argparse.ExistingFile, file, true},
+		{argparse.ExistingFile, dir, false},
+		{argparse.ExistingFile, missing, false},
+		{argparse.ExistingDir, dir, true},
+		{argparse.ExistingDir, file, false},
+		{argparse.WritablePath, file, true},
+		{argparse.WritablePath, missing, true},
+		{argparse.WritablePath, dir, false},
+		{argparse.WritablePath, filepath.Join(missing, "b.txt"), false},
+		{argparse.WritablePath, filepath.Join(readOnly, "b.txt"), false},
+	} {
+		v, err := tc.parse(tc.path)
+		if tc.ok && (err != nil || v != tc.path) {
+			t.Fatalf("expected %q to be valid, got %v, %v", tc.path, v, err)
+		}
+		if !tc.ok && err == nil {
+			t.Fatalf("expected %q to be invalid", tc.path)
+		}
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Fatal("expected WritablePath not to create the file")
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 2 {
+		t.Fatalf("expected WritablePath not to write to %q: %v, %v",
+			dir, entries, err)
+	}
+}
+
+func TestExpandGlobs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.log", "b.log", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	files := p.MustAddArgument(
+		argparse.Dest("files"),
+		argparse.Nargs(argparse.OneOrMore),
+		argparse.ExpandGlobs)
+
+	ns, err := p.ParseArgs(filepath.Join(dir, "*.log"), "other.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs := ns.MustGet(files).([]interface{})
+	want := []interface{}{
+		filepath.Join(dir, "a.log"),
+		filepath.Join(dir, "b.log"),
+		"other.txt",
+	}
+	if len(vs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, vs)
+	}
+	for i := range want {
+		if vs[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, vs)
+		}
+	}
+	if _, err := p.ParseArgs(filepath.Join(dir, "*.csv")); err == nil {
+		t.Fatal("expected error from a pattern that matches nothing")
+	}
+	if _, err := p.AddArgument(
+		argparse.Dest("logs"),
+		argparse.Type(argparse.Int),
+		argparse.ExpandGlobs); err == nil {
+		t.Fatal("expected error from ExpandGlobs replacing a Type")
+	}
+}
+
+func TestEnum(t *testing.T) {
+	t.Parallel()
+
+	parse := argparse.Enum(map[string]interface{}{
+		"fast": 1,
+		"slow": 2,
+	})
+	v, err := parse("slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 2 {
+		t.Fatalf("expected 2, got %v", v)
+	}
+	_, err = parse("medium")
+	if err == nil || !strings.Contains(err.Error(), "choose from fast, slow") {
+		t.Fatalf("expected error listing the valid names, got %v", err)
+	}
+}
+
+// level is a TextUnmarshaler used to test binding to such types.
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.Errorf("invalid level: %q", text)
+	}
+	return nil
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	var (
+		lvl  level
+		lvls []level
+		addr net.IP
+	)
+	p.MustAddArgument(
+		argparse.OptionStrings("--level"),
+		argparse.Action("store")).MustBind(&lvl)
+	p.MustAddArgument(
+		argparse.OptionStrings("--levels"),
+		argparse.Action("store"),
+		argparse.Nargs(argparse.OneOrMore)).MustBind(&lvls)
+	p.MustAddArgument(
+		argparse.OptionStrings("--addr"),
+		argparse.Action("store")).MustBind(&addr)
+
+	if _, err := p.ParseArgs(
+		"--level", "high", "--levels", "low", "high",
+		"--addr", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if lvl != 2 || len(lvls) != 2 || lvls[0] != 1 || lvls[1] != 2 {
+		t.Fatalf("unexpected levels: %v, %v", lvl, lvls)
+	}
+	if !addr.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Fatalf("unexpected address: %v", addr)
+	}
+	if _, err := p.ParseArgs("--level", "medium"); err == nil {
+		t.Fatal("expected error from UnmarshalText")
+	}
+}
+
+// stringList is a flag.Value used to test FlagValue.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// boolFlag is a bool flag.Value used to test FlagValue.
+type boolFlag bool
+
+func (b *boolFlag) String() string   { return strconv.FormatBool(bool(*b)) }
+func (b *boolFlag) IsBoolFlag() bool { return true }
+
+func (b *boolFlag) Set(v string) error {
+	x, err := strconv.ParseBool(v)
+	*b = boolFlag(x)
+	return err
+}
+
+func TestFlagValue(t *testing.T) {
+	t.Parallel()
+
+	var (
+		tags    stringList
+		verbose boolFlag
+	)
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	tag := p.MustAddArgument(
+		argparse.OptionStrings("--tag"),
+		argparse.FlagValue(&tags))
+	p.MustAddArgument(
+		argparse.OptionStrings("-v"),
+		argparse.FlagValue(&verbose))
+
+	ns, err := p.ParseArgs("--tag", "a", "-v", "--tag", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags.String() != "a,b" || !verbose {
+		t.Fatalf("unexpected values: %v, %v", tags, verbose)
+	}
+	if ns.MustGet(tag) != &tags {
+		t.Fatalf("expected the flag.Value in the namespace: %v", ns)
+	}
+	if _, err := p.AddArgument(
+		argparse.OptionStrings("--other"),
+		argparse.Type(argparse.Int),
+		argparse.FlagValue(&tags)); err == nil {
+		t.Fatal("expected an error setting the type twice")
+	}
+}
+
+func TestGenericParsers(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.Strict)
+	var d time.Duration
+	p.MustAddArgument(
+		argparse.OptionStrings("--timeout"),
+		argparse.Action("store"),
+		argparse.TypeFunc(time.ParseDuration)).MustBind(&d)
+	var s string
+	if err := p.MustAddArgument(
+		argparse.OptionStrings("--delay"),
+		argparse.Action("store"),
+		argparse.TypeFunc(time.ParseDuration)).Bind(&s); err == nil {
+		t.Fatal("expected error binding a duration to a string")
+	}
+	if _, err := p.ParseArgs("--timeout", "5s"); err != nil {
+		t.Fatal(err)
+	}
+	if d != 5*time.Second {
+		t.Fatalf("unexpected duration: %v", d)
+	}
+
+	parseInt := argparse.Typed[int](argparse.Int)
+	if n, err := parseInt("42"); err != nil || n != 42 {
+		t.Fatalf("unexpected result: %v, %v", n, err)
+	}
+	if _, err := argparse.Typed[string](argparse.Int)("42"); err == nil {
+		t.Fatal("expected error from mismatched type")
+	}
+	if _, err := p.AddArgument(
+		argparse.OptionStrings("--delay"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int),
+		argparse.TypeFunc(time.ParseDuration)); err == nil {
+		t.Fatal("expected an error setting the type twice")
+	}
+}
+
+func TestIntegerParsers(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		parse argparse.ValueParser
+		s     string
+		v     interface{}
+	}{
+		{argparse.Int, "1_000", 1000},
+		{argparse.Int, "-0x10", -16},
+		{argparse.Int, "010", 10},
+		{argparse.Int, "-010", -10},
+		{argparse.Uint16, "0080", uint16(80)},
+		{argparse.Int8, "0b111", int8(7)},
+		{argparse.Int64, "0o17", int64(15)},
+		{argparse.Uint, "42", uint(42)},
+		{argparse.Uint8, "0xff", uint8(255)},
+		{argparse.Uint16, "65535", uint16(65535)},
+		{argparse.Uint32, "0b1", uint32(1)},
+		{argparse.Uint64, "18446744073709551615", uint64(18446744073709551615)},
+		{argparse.Float32, "1.5", float32(1.5)},
+		{argparse.Float64, "1e3", float64(1000)},
+	} {
+		v, err := tc.parse(tc.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.v {
+			t.Fatalf("expected %q to be %v (%[2]T), got %v (%[3]T)", tc.s, tc.v, v)
+		}
+	}
+	for _, tc := range []struct {
+		parse argparse.ValueParser
+		s     string
+	}{
+		{argparse.Int8, "128"},
+		{argparse.Uint8, "256"},
+		{argparse.Uint, "-1"},
+		{argparse.Int, "12abc"},
+	} {
+		if _, err := tc.parse(tc.s); err == nil {
+			t.Fatalf("expected error parsing %q", tc.s)
+		}
+	}
+	_, err := argparse.Uint8("256")
+	if err == nil || !strings.Contains(err.Error(), "out of range for uint8") {
+		t.Fatalf("expected out of range error, got %v", err)
+	}
+	if v, err := argparse.Port(true)("08080"); err != nil || v != uint16(8080) {
+		t.Fatalf("expected port 8080, got %v, %v", v, err)
+	}
+	if v, err := argparse.BigInt("010"); err != nil || v.(*big.Int).Int64() != 10 {
+		t.Fatalf("expected big 10, got %v, %v", v, err)
+	}
+}
+
+func TestBool(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"true", "T", "Yes", "y", "ON", "1"} {
+		if v, err := argparse.Bool(s); err != nil || v != true {
+			t.Fatalf("expected %q to be true, got %v, %v", s, v, err)
+		}
+	}
+	for _, s := range []string{"FALSE", "f", "no", "N", "off", "0"} {
+		if v, err := argparse.Bool(s); err != nil || v != false {
+			t.Fatalf("expected %q to be false, got %v, %v", s, v, err)
+		}
+	}
+	for _, s := range []string{"", "2", "yep"} {
+		if _, err := argparse.Bool(s); err == nil {
+			t.Fatalf("expected error parsing %q", s)
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	t.Parallel()
+
+	if v, err := argparse.IntRange(1, 10)("10"); err != nil || v != 10 {
+		t.Fatalf("unexpected result: %v, %v", v, err)
+	}
+	_, err := argparse.IntRange(1, 10)("11")
+	if err == nil || !strings.Contains(err.Error(), "[1, 10]") {
+		t.Fatalf("expected error naming the range, got %v", err)
+	}
+	for _, s := range []string{"-0.1", "1.5", "NaN"} {
+		if _, err := argparse.Float64Range(0, 1)(s); err == nil {
+			t.Fatalf("expected error parsing %q", s)
+		}
+	}
+
+	var sb strings.Builder
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.Stderr(&sb))
+	workers := p.MustAddArgument(
+		argparse.OptionStrings("--workers"),
+		argparse.Action("store"),
+		argparse.IntRangeType(1, 8),
+		argparse.Clamp)
+	ratio := p.MustAddArgument(
+		argparse.OptionStrings("--ratio"),
+		argparse.Action("store"),
+		argparse.Float64RangeType(0, 1))
+
+	ns, err := p.ParseArgs("--workers", "16", "--ratio", "0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.MustGet(workers) != 8 || ns.MustGet(ratio) != 0.5 {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+	if !strings.Contains(sb.String(), "using 8") {
+		t.Fatalf("expected clamping warning, got %q", sb.String())
+	}
+	if _, err := p.ParseArgs("--ratio", "2"); err == nil {
+		t.Fatal("expected error from out of range ratio")
+	}
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(help, "(between 1 and 8)") || !strings.Contains(help, "(between 0 and 1)") {
+		t.Fatalf("expected ranges in help:\n%s", help)
+	}
+	for _, rt := range []argparse.ArgumentOption{
+		argparse.IntRangeType(1, 8),
+		argparse.Float64RangeType(0, 1),
+	} {
+		if _, err := p.AddArgument(
+			argparse.OptionStrings("--other"),
+			argparse.Type(argparse.String),
+			rt); err == nil {
+			t.Fatal("expected error from a range replacing a Type")
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	nonEmpty := func(v interface{}) error {
+		if v == "" {
+			return errors.Errorf("must not be empty")
+		}
+		return nil
+	}
+	noSpaces := func(v interface{}) error {
+		if strings.Contains(v.(string), " ") {
+			return errors.Errorf("must not contain spaces")
+		}
+		return nil
+	}
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	name := p.MustAddArgument(
+		argparse.OptionStrings("--name"),
+		argparse.Action("store"),
+		argparse.Validate(nonEmpty, noSpaces))
+
+	ns, err := p.ParseArgs("--name", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.MustGet(name) != "x" {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+	for _, s := range []string{"", "a b"} {
+		_, err := p.ParseArgs("--name", s)
+		if err == nil || !strings.Contains(err.Error(), `argument "name"`) {
+			t.Fatalf("expected argument error validating %q, got %v", s, err)
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	t.Setenv("ARGPARSE_TEST_DIR", "data")
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	level := p.MustAddArgument(
+		argparse.OptionStrings("--level"),
+		argparse.Action("store"),
+		argparse.Transform(argparse.TrimSpace, argparse.ToLower),
+		argparse.Choices(
+			argparse.Choice{Key: "low", Value: 1},
+			argparse.Choice{Key: "high", Value: 2}))
+	dir := p.MustAddArgument(
+		argparse.OptionStrings("--dir"),
+		argparse.Action("store"),
+		argparse.Transform(argparse.ExpandHome, argparse.ExpandEnv))
+
+	ns, err := p.ParseArgs("--level", " HIGH ", "--dir", "~/$ARGPARSE_TEST_DIR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.MustGet(level) != 2 {
+		t.Fatalf("unexpected level: %v", ns.MustGet(level))
+	}
+	if v := ns.MustGet(dir); v != home+"/data" {
+		t.Fatalf("unexpected dir: %v", v)
+	}
+}
+
+func TestElemType(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	ports := p.MustAddArgument(
+		argparse.OptionStrings("--port"),
+		argparse.Action("append"),
+		argparse.Nargs(1),
+		argparse.ElemType(argparse.Int))
+	names := p.MustAddArgument(
+		argparse.Dest("names"),
+		argparse.Nargs(argparse.OneOrMore),
+		argparse.ElemType(argparse.String))
+	var bound []int
+	ports.MustBind(&bound)
+
+	ns, err := p.ParseArgs("--port", "80", "a", "b", "--port", "443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vs, ok := ns.MustGet(ports).([]int); !ok || len(vs) != 2 || vs[1] != 443 {
+		t.Fatalf("expected []int, got %#v", ns.MustGet(ports))
+	}
+	if vs, ok := ns.MustGet(names).([]string); !ok || len(vs) != 2 || vs[0] != "a" {
+		t.Fatalf("expected []string, got %#v", ns.MustGet(names))
+	}
+	if len(bound) != 2 || bound[0] != 80 {
+		t.Fatalf("unexpected bound ports: %v", bound)
+	}
+	if _, err := p.AddArgument(
+		argparse.OptionStrings("--other"),
+		argparse.Type(argparse.String),
+		argparse.ElemType(argparse.Int)); err == nil {
+		t.Fatal("expected error from ElemType replacing a Type")
+	}
+}
+
+func TestBigNumbers(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.Strict)
+	var (
+		amount *big.Int
+		price  *big.Rat
+	)
+	p.MustAddArgument(
+		argparse.OptionStrings("--amount"),
+		argparse.Action("store"),
+		argparse.Type(argparse.BigInt)).MustBind(&amount)
+	p.MustAddArgument(
+		argparse.OptionStrings("--price"),
+		argparse.Action("store"),
+		argparse.Type(argparse.BigRat)).MustBind(&price)
+
+	if _, err := p.ParseArgs(
+		"--amount", "0x1_0000_0000_0000_0000",
+		"--price", "0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if amount.String() != "18446744073709551616" {
+		t.Fatalf("unexpected amount: %v", amount)
+	}
+	if price.String() != "1/10" {
+		t.Fatalf("unexpected price: %v", price)
+	}
+	if _, err := argparse.BigInt("1.5"); err == nil {
+		t.Fatal("expected error parsing a fraction as an integer")
+	}
+	if _, err := argparse.BigRat("1/0"); err == nil {
+		t.Fatal("expected error parsing a zero denominator")
+	}
+}
+
+func TestPort(t *testing.T) {
+	t.Parallel()
+
+	if v, err := argparse.Port(true)("80"); err != nil || v != uint16(80) {
+		t.Fatalf("unexpected result: %v, %v", v, err)
+	}
+	if v, err := argparse.Port(false)("8080"); err != nil || v != uint16(8080) {
+		t.Fatalf("unexpected result: %v, %v", v, err)
+	}
+	for _, tc := range []struct {
+		allowPrivileged bool
+		s               string
+	}{
+		{true, "0"},
+		{true, "65536"},
+		{true, "http"},
+		{false, "443"},
+	} {
+		if _, err := argparse.Port(tc.allowPrivileged)(tc.s); err == nil {
+			t.Fatalf("expected error parsing %q", tc.s)
+		}
+	}
+}
+
+func TestParseInto(t *testing.T) {
+	t.Parallel()
+
+	type network struct {
+		Addr net.IP `arg:"--addr" help:"address to listen on" default:"127.0.0.1"`
+	}
+	var opts struct {
+		Count   int           `arg:"-c,--count" help:"number of times" default:"10"`
+		Verbose bool          `arg:"-v,--verbose"`
+		Timeout time.Duration `arg:"--timeout" default:"1s"`
+		Tags    []string      `arg:"--tag" metavar:"TAG"`
+		Level   level         `arg:"--level"`
+		Files   []string      `arg:"files"`
+		Ignored string
+		network
+	}
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.Strict)
+	_, err := p.ParseInto(&opts,
+		"-v", "--tag", "a", "--level", "high", "--tag", "b", "x", "y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Count != 10 || !opts.Verbose || opts.Timeout != time.Second ||
+		len(opts.Tags) != 2 || opts.Level != 2 || len(opts.Files) != 2 ||
+		!opts.Addr.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(help, "number of times") || !strings.Contains(help, "--tag TAG") {
+		t.Fatalf("expected tagged help:\n%s", help)
+	}
+	if err := p.AddStructArguments(opts); err == nil {
+		t.Fatal("expected error adding arguments of a non-pointer")
+	}
+}
+
+func TestGenericGet(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	n := p.MustAddArgument(
+		argparse.OptionStrings("-n"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+	names := p.MustAddArgument(
+		argparse.Dest("names"),
+		argparse.Nargs(argparse.OneOrMore))
+	missing := p.MustAddArgument(
+		argparse.OptionStrings("--missing"),
+		argparse.Action("store"))
+
+	ns, err := p.ParseArgs("-n", "300", "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := argparse.MustGet[int](ns, n); v != 300 {
+		t.Fatalf("unexpected int: %v", v)
+	}
+	if v := argparse.MustGet[int64](ns, n); v != 300 {
+		t.Fatalf("unexpected int64: %v", v)
+	}
+	if v := argparse.MustGet[float64](ns, n); v != 300 {
+		t.Fatalf("unexpected float64: %v", v)
+	}
+	if v := argparse.MustGet[[]string](ns, names); len(v) != 2 || v[1] != "b" {
+		t.Fatalf("unexpected names: %v", v)
+	}
+	if _, err := argparse.Get[uint8](ns, n); err == nil {
+		t.Fatal("expected error converting 300 to uint8")
+	}
+	if _, err := argparse.Get[string](ns, n); err == nil {
+		t.Fatal("expected error converting an int to a string")
+	}
+	if _, err := argparse.Get[string](ns, missing); err == nil {
+		t.Fatal("expected error getting a missing argument")
+	}
+
+	ns, err = p.ParseArgs("-n", "-1", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := argparse.Get[uint](ns, n); err == nil {
+		t.Fatal("expected error converting -1 to uint")
+	}
+}
+
+func TestNamespaceJSON(t *testing.T) {
+	t.Parallel()
+
+	ns := argparse.Namespace{
+		"name":  "x",
+		"count": 3,
+		"ratio": 0.5,
+		"files": []interface{}{"a", "b"},
+		"on":    true,
+	}
+	b, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"count":3,"files":["a","b"],"name":"x","on":true,"ratio":0.5}`
+	if string(b) != want {
+		t.Fatalf("expected %s, got %s", want, b)
+	}
+	var got argparse.Namespace
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["count"] != 3 || got["ratio"] != 0.5 || got["name"] != "x" ||
+		got["on"] != true || len(got["files"].([]interface{})) != 2 {
+		t.Fatalf("unexpected namespace: %#v", got)
+	}
+}
+
+func TestNestedNamespaces(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("tool"),
+		argparse.NestedNamespaces)
+	p.MustAddArgument(
+		argparse.OptionStrings("-v", "--verbose"),
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.Persistent)
+	sub := p.MustAddSubparsers(argparse.Dest("command"))
+	build := sub.MustAddParser("build")
+	build.MustAddArgument(
+		argparse.OptionStrings("--target"),
+		argparse.Action("store"))
+	deploy := sub.MustAddParser("deploy")
+	deploy.MustAddArgument(
+		argparse.OptionStrings("--target"),
+		argparse.Action("store"))
+
+	ns, err := p.ParseArgs("build", "--target", "linux", "-v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns["command"] != "build" || ns["verbose"] != true {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+	if _, ok := ns["target"]; ok {
+		t.Fatalf("expected target only in the sub-namespace: %v", ns)
+	}
+	if v := ns.Sub("build")["target"]; v != "linux" {
+		t.Fatalf("unexpected build target: %v", v)
+	}
+	if ns.Sub("deploy") != nil {
+		t.Fatalf("unexpected deploy namespace: %v", ns.Sub("deploy"))
+	}
+}
+
+func TestNamespaceKeys(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.NoHelp)
+	p.MustAddArgument(
+		argparse.OptionStrings("--zeta"),
+		argparse.Action("store"),
+		argparse.Default("z"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--alpha"),
+		argparse.Action("store"),
+		argparse.Default("a"))
+	ns, err := p.ParseArgs("--alpha", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns["extra"] = 1
+
+	if keys := strings.Join(ns.Keys(), " "); keys != "alpha extra zeta" {
+		t.Fatalf("unexpected keys: %q", keys)
+	}
+	if keys := strings.Join(ns.Keys(p), " "); keys != "zeta alpha extra" {
+		t.Fatalf("unexpected definition order: %q", keys)
+	}
+	var ranged []string
+	ns.Range(func(k string, v interface{}) bool {
+		ranged = append(ranged, k)
+		return k != "extra"
+	})
+	if strings.Join(ranged, " ") != "alpha extra" {
+		t.Fatalf("unexpected range: %v", ranged)
+	}
+	ranged = ranged[:0]
+	ns.Range(func(k string, v interface{}) bool {
+		ranged = append(ranged, k)
+		return k != "alpha"
+	}, p)
+	if strings.Join(ranged, " ") != "zeta alpha" {
+		t.Fatalf("unexpected range in definition order: %v", ranged)
+	}
+}
+
+func TestWasSet(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	workers := p.MustAddArgument(
+		argparse.OptionStrings("--workers"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int),
+		argparse.Default(4))
+	debug := p.MustAddArgument(
+		argparse.OptionStrings("--debug"),
+		argparse.ActionFunc(argparse.BooleanOptional))
+	sub := p.MustAddSubparsers(argparse.Dest("command"))
+	run := sub.MustAddParser("run")
+	fast := run.MustAddArgument(
+		argparse.OptionStrings("--fast"),
+		argparse.ActionFunc(argparse.StoreTrue))
+
+	ns, set, err := p.ParseArgsSet("--no-debug", "run", "--fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.WasSet(workers) || !set.WasSet(debug) || !set.WasSet(fast) {
+		t.Fatalf("unexpected set arguments: %v", set)
+	}
+	if ns.MustGet(workers) != 4 {
+		t.Fatalf("expected default workers: %v", ns)
+	}
+	if keys := strings.Join(ns.Keys(), " "); keys != "command debug fast workers" {
+		t.Fatalf("unexpected keys: %q", keys)
+	}
+
+	_, set, err = p.ParseArgsSet("--workers", "4", "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !set.WasSet(workers) || set.WasSet(fast) {
+		t.Fatalf("unexpected set arguments: %v", set)
+	}
+
+	p.NestedNamespaces = true
+	ns, set, err = p.ParseArgsSet("run", "--fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !set.WasSet(fast) || ns.Sub("run") == nil {
+		t.Fatalf("unexpected nested namespace: %v, %v", ns, set)
+	}
+}
+
+func TestNamespaceMerge(t *testing.T) {
+	t.Parallel()
+
+	base := argparse.Namespace{
+		"name":   "cli",
+		"tags":   []interface{}{"a"},
+		"labels": map[string]interface{}{"env": "prod", "team": "x"},
+	}
+	config := argparse.Namespace{
+		"name":   "config",
+		"tags":   []interface{}{"b", "c"},
+		"labels": map[string]interface{}{"env": "dev", "zone": "1"},
+		"port":   8080,
+	}
+
+	ns := argparse.Namespace{}
+	ns.Merge(base, true)
+	ns.Merge(config, false)
+	labels := ns["labels"].(map[string]interface{})
+	if ns["name"] != "cli" || len(ns["tags"].([]interface{})) != 1 ||
+		ns["port"] != 8080 || labels["env"] != "prod" ||
+		labels["zone"] != "1" || labels["team"] != "x" {
+		t.Fatalf("unexpected merge without overwrite: %v", ns)
+	}
+	ns.Merge(config, true)
+	labels = ns["labels"].(map[string]interface{})
+	if ns["name"] != "config" || len(ns["tags"].([]interface{})) != 2 ||
+		labels["env"] != "dev" || labels["team"] != "x" {
+		t.Fatalf("unexpected merge with overwrite: %v", ns)
+	}
+	ns["tags"].([]interface{})[0] = "changed"
+	if config["tags"].([]interface{})[0] != "b" {
+		t.Fatal("expected merged slices to be copied")
+	}
+	if base["labels"].(map[string]interface{})["zone"] != nil {
+		t.Fatal("expected merged maps to be copied")
+	}
+
+	typed := argparse.Namespace{
+		"names":  []string{"a"},
+		"ports":  []int{80},
+		"env":    map[string]string{"k": "v"},
+		"groups": map[string][]string{"g": {"x"}},
+	}
+	ns = argparse.Namespace{}
+	ns.Merge(typed, true)
+	ns["names"].([]string)[0] = "changed"
+	ns["ports"].([]int)[0] = 0
+	ns["env"].(map[string]string)["k"] = "changed"
+	ns["groups"].(map[string][]string)["g"][0] = "changed"
+	if typed["names"].([]string)[0] != "a" || typed["ports"].([]int)[0] != 80 ||
+		typed["env"].(map[string]string)["k"] != "v" ||
+		typed["groups"].(map[string][]string)["g"][0] != "x" {
+		t.Fatalf("expected typed slices and maps to be copied: %v", typed)
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	sub := p.MustAddSubparsers(argparse.Dest("command"))
+	build := sub.MustAddParser("build")
+	jobs := build.MustAddArgument(
+		argparse.OptionStrings("-j", "--jobs"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+	build.SetDefaults(map[string]interface{}{
+		"jobs":    2,
+		"handler": "build",
+	})
+
+	if v, ok := build.GetDefault("jobs"); !ok || v != 2 {
+		t.Fatalf("unexpected jobs default: %v, %v", v, ok)
+	}
+	if v, ok := build.GetDefault("handler"); !ok || v != "build" {
+		t.Fatalf("unexpected handler default: %v, %v", v, ok)
+	}
+	if _, ok := build.GetDefault("missing"); ok {
+		t.Fatal("unexpected default of a missing key")
+	}
+	ns, err := p.ParseArgs("build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.MustGet(jobs) != 2 || ns["handler"] != "build" {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+	ns, err = p.ParseArgs("build", "-j", "8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.MustGet(jobs) != 8 {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+}
+
+func TestBindFunc(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+	var level int64
+	p.MustAddArgument(
+		argparse.OptionStrings("-v", "--verbosity"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int)).MustBind(func(v int64) error {
+		if v < 0 {
+			return errors.New("verbosity must not be negative")
+		}
+		level = v
+		return nil
+	})
+	var names []string
+	p.MustAddArgument(
+		argparse.Dest("names"),
+		argparse.Nargs(argparse.ZeroOrMore)).MustBind(func(v []string) {
+		names = v
+	})
+
+	if _, err := p.ParseArgs("-v", "3", "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if level != 3 || len(names) != 2 || names[1] != "b" {
+		t.Fatalf("unexpected bound values: %d, %v", level, names)
+	}
+	if _, err := p.ParseArgs("-v", "-1"); err == nil ||
+		!strings.Contains(err.Error(), "must not be negative") {
+		t.Fatalf("expected the callback's error, not %v", err)
+	}
+
+	a := argparse.MustNewArgumentParser().MustAddArgument(
+		argparse.OptionStrings("-n"), argparse.Action("store"))
+	if err := a.Bind(func(a, b string) {}); err == nil {
+		t.Fatal("expected an error binding a func of two parameters")
+	}
+	if err := a.Bind(func(string) int { return 0 }); err == nil {
+		t.Fatal("expected an error binding a func returning an int")
+	}
+}
+
+func TestBindMap(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("tool"), argparse.Strict)
+	var limits map[string]int
+	p.MustAddArgument(
+		argparse.OptionStrings("--limit"),
+		argparse.ActionFunc(argparse.StoreMap),
+		argparse.Type(argparse.Int)).MustBind(&limits)
+	var timeouts map[string]time.Duration
+	p.MustAddArgument(
+		argparse.OptionStrings("--timeout"),
+		argparse.ActionFunc(argparse.Append),
+		argparse.Nargs(1),
+		argparse.Type(argparse.String)).MustBind(&timeouts)
+	var flags map[string]bool
+	p.MustAddArgument(
+		argparse.OptionStrings("--flag"),
+		argparse.Action("store"),
+		argparse.Type(argparse.String)).MustBind(&flags)
+
+	if _, err := p.ParseArgs(
+		"--limit", "cpu=2", "--limit", "mem=512",
+		"--timeout", "read=5s", "--timeout", "write=1m",
+		"--flag", "debug=yes"); err != nil {
+		t.Fatal(err)
+	}
+	if len(limits) != 2 || limits["cpu"] != 2 || limits["mem"] != 512 {
+		t.Fatalf("unexpected limits: %v", limits)
+	}
+	if len(timeouts) != 2 || timeouts["read"] != 5*time.Second ||
+		timeouts["write"] != time.Minute {
+		t.Fatalf("unexpected timeouts: %v", timeouts)
+	}
+	if len(flags) != 1 || !flags["debug"] {
+		t.Fatalf("unexpected flags: %v", flags)
+	}
+	if _, err := p.ParseArgs("--timeout", "read=soon"); err == nil {
+		t.Fatal("expected an error from an invalid duration")
+	}
+	if _, err := p.ParseArgs("--timeout", "read"); err == nil {
+		t.Fatal("expected an error from a value without a key")
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	count := p.MustAddArgument(
+		argparse.OptionStrings("-n", "--count"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int),
+		argparse.Default(3))
+	p.MustAddArgument(
+		argparse.OptionStrings("-v", "--verbose"),
+		argparse.ActionFunc(argparse.StoreTrue))
+	var bound int
+	count.MustBind(&bound)
+	fail := false
+	p.Finalize(func(ns argparse.Namespace) error {
+		if v := ns.MustGet(count); v != 3 {
+			t.Errorf("expected the default before finalizing, not %v", v)
+		}
+		if bound != 0 {
+			t.Errorf("expected the target to be unset, not %d", bound)
+		}
+		if fail {
+			return errors.New("finalize failed")
+		}
+		ns.Set(count, 5)
+		return nil
+	})
+
+	if _, err := p.ParseArgs("-v"); err != nil {
+		t.Fatal(err)
+	}
+	if bound != 5 {
+		t.Fatalf("expected the finalized value 5 but got %d", bound)
+	}
+	bound, fail = 0, true
+	if _, err := p.ParseArgs("-v"); err == nil ||
+		!strings.Contains(err.Error(), "finalize failed") {
+		t.Fatalf("expected the finalizer's error, not %v", err)
+	}
+	if bound != 0 {
+		t.Fatalf("expected the target to be unset after the error, not %d", bound)
+	}
+}
+
+func TestParseKnownArgs(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	count := p.MustAddArgument(
+		argparse.OptionStrings("-n", "--count"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+	file := p.MustAddArgument(
+		argparse.Dest("file"),
+		argparse.Nargs(1))
+
+	ns, extras, err := p.ParseKnownArgs(
+		"--color", "-n", "3", "in.txt", "--fast", "out.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(count); v != 3 {
+		t.Fatalf("expected count 3 but got %v", v)
+	}
+	if v := ns.MustGet(file); v != "in.txt" {
+		t.Fatalf("expected file in.txt but got %v", v)
+	}
+	expect := []string{"--color", "--fast", "out.txt"}
+	if strings.Join(extras, " ") != strings.Join(expect, " ") {
+		t.Fatalf("expected extras %v but got %v", expect, extras)
+	}
+	if _, err := p.ParseArgs("--color", "in.txt"); err == nil {
+		t.Fatal("expected ParseArgs to reject the unknown option")
+	}
+}
+
+func TestHelpLimits(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--tag"),
+		argparse.MaxOccurrences(4),
+		argparse.ActionFunc(argparse.Append),
+		argparse.Nargs(1),
+		argparse.Help("A tag."))
+	p.MustAddArgument(
+		argparse.OptionStrings("--point"),
+		argparse.Action("store"),
+		argparse.Nargs(2),
+		argparse.Help("A point."))
+	p.MustAddArgument(
+		argparse.OptionStrings("-v"),
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.MinOccurrences(1),
+		argparse.MaxOccurrences(3),
+		argparse.Help("Verbose output."))
+
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"A tag. (up to 4 values)",
+		"A point. (2 values)",
+		"Verbose output. (at least 1 times) (at most 3 times)",
+	} {
+		if !strings.Contains(help, expect) {
+			t.Fatalf("expected %q in help:\n%s", expect, help)
+		}
+	}
+}
+
+func TestGNUErrors(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("tool"), argparse.GNUErrors)
+	p.MustAddArgument(
+		argparse.OptionStrings("-o", "--output"),
+		argparse.Action("store"))
+
+	for _, tc := range []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"--color"}, "tool: unrecognized option '--color'"},
+		{[]string{"-x"}, "tool: invalid option -- 'x'"},
+		{[]string{"--output"}, "tool: option '--output' requires an argument"},
+		{[]string{"-o"}, "tool: option requires an argument -- 'o'"},
+	} {
+		_, err := p.ParseArgs(tc.args...)
+		if err == nil {
+			t.Fatalf("%v: expected an error", tc.args)
+		}
+		if msg := strings.SplitN(err.Error(), "\n", 2)[0]; msg != tc.expect {
+			t.Fatalf("%v: expected %q but got %q", tc.args, tc.expect, msg)
+		}
+	}
+}
+
+func TestOverwrite(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	level := p.MustAddArgument(
+		argparse.OptionStrings("-l", "--level"),
+		argparse.ActionFunc(argparse.Overwrite),
+		argparse.Nargs(1),
+		argparse.Type(argparse.Int))
+
+	ns, err := p.ParseArgs("-l", "1", "--level", "2", "-l", "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(level); v != 3 {
+		t.Fatalf("expected the last occurrence 3 but got %v", v)
+	}
+}
+
+func TestAppendConst(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	str := p.MustAddArgument(
+		argparse.OptionStrings("--str"),
+		argparse.ActionFunc(argparse.AppendConst),
+		argparse.Dest("types"),
+		argparse.Nargs(0),
+		argparse.Const("str"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--int"),
+		argparse.ActionFunc(argparse.AppendConst),
+		argparse.Dest("types"),
+		argparse.Nargs(0),
+		argparse.Const("int"))
+
+	ns, err := p.ParseArgs("--str", "--int", "--str")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := ns.MustGetStrings(str); strings.Join(types, " ") != "str int str" {
+		t.Fatalf("expected [str int str] but got %v", types)
+	}
+}
+
+func TestValidateNamespace(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	p.MustAddArgument(
+		argparse.OptionStrings("--level"),
+		argparse.Action("store"),
+		argparse.ChoiceValues("debug", "info"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--count"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int),
+		argparse.Required)
+
+	for _, tc := range []struct {
+		ns    argparse.Namespace
+		valid bool
+	}{
+		{argparse.Namespace{"level": "info", "count": 3}, true},
+		{argparse.Namespace{"count": 3}, true},
+		{argparse.Namespace{"level": "trace", "count": 3}, false},
+		{argparse.Namespace{"level": "info", "count": "3"}, false},
+		{argparse.Namespace{"level": "info"}, false},
+	} {
+		if err := p.ValidateNamespace(tc.ns); (err == nil) != tc.valid {
+			t.Fatalf("%v: expected valid: %t but got %v", tc.ns, tc.valid, err)
+		}
+	}
+}
+
+func TestValidateNamespaceValues(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	size := p.MustAddArgument(
+		argparse.OptionStrings("--size"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int64))
+	ratio := p.MustAddArgument(
+		argparse.OptionStrings("--ratio"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Float64))
+	p.MustAddArgument(
+		argparse.OptionStrings("--port"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int),
+		argparse.Validate(func(v interface{}) error {
+			if v.(int) < 1024 {
+				return errors.Errorf("port %v is reserved", v)
+			}
+			return nil
+		}))
+
+	var ns argparse.Namespace
+	if err := json.Unmarshal([]byte(`{"size": 1.0, "ratio": 2}`), &ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ValidateNamespace(ns); err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(size); v != int64(1) {
+		t.Fatalf("expected int64 1 but got %[1]v (type: %[1]T)", v)
+	}
+	if v := ns.MustGet(ratio); v != 2.0 {
+		t.Fatalf("expected float64 2 but got %[1]v (type: %[1]T)", v)
+	}
+
+	for _, tc := range []struct {
+		ns    argparse.Namespace
+		valid bool
+	}{
+		{argparse.Namespace{"size": float64(1)}, true},
+		{argparse.Namespace{"size": 1.5}, false},
+		{argparse.Namespace{"ratio": 3}, true},
+		{argparse.Namespace{"port": 8080}, true},
+		{argparse.Namespace{"port": 80}, false},
+	} {
+		if err := p.ValidateNamespace(tc.ns); (err == nil) != tc.valid {
+			t.Fatalf("%v: expected valid: %t but got %v", tc.ns, tc.valid, err)
+		}
+	}
+}
+
+func TestValidateNamespaceFileType(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "out.txt")
+	if err := os.WriteFile(name, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := argparse.MustNewArgumentParser()
+	p.MustAddArgument(
+		argparse.OptionStrings("--out"),
+		argparse.Action("store"),
+		argparse.Type(argparse.FileType(os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)))
+
+	_ = p.ValidateNamespace(argparse.Namespace{"out": name})
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "keep" {
+		t.Fatalf("expected %q to be untouched but it has %q", name, b)
+	}
+}
+
+func TestExtend(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	nums := p.MustAddArgument(
+		argparse.OptionStrings("-n"),
+		argparse.ActionFunc(argparse.Extend),
+		argparse.Nargs(argparse.OneOrMore),
+		argparse.Type(argparse.Int))
+
+	ns, err := p.ParseArgs("-n", "1", "2", "-n", "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, ok := ns.MustGet(nums).([]interface{})
+	if !ok || len(vs) != 3 || vs[0] != 1 || vs[1] != 2 || vs[2] != 3 {
+		t.Fatalf("expected [1 2 3] but got %v", ns.MustGet(nums))
+	}
+}
+
+func TestParseValuesWithoutPromptsOrBinding(t *testing.T) {
+	t.Parallel()
+
+	stdin := strings.NewReader("y\n")
+	p := argparse.MustNewArgumentParser(
+		argparse.Stdin(stdin),
+		argparse.Stderr(io.Discard))
+	p.MustAddArgument(
+		argparse.OptionStrings("--force"),
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.Confirm("Really delete everything?"))
+	count := p.MustAddArgument(
+		argparse.OptionStrings("--count"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+	bound := 7
+	count.MustBind(&bound)
+
+	_, err := p.ParseValues(url.Values{"count": {"3"}})
+	if err == nil || !strings.Contains(err.Error(), "requires confirmation") {
+		t.Fatalf("expected a confirmation error, not %v", err)
+	}
+	if stdin.Len() != 2 {
+		t.Fatal("expected nothing to be read from Stdin")
+	}
+	ns, err := p.ParseValues(url.Values{"count": {"3"}, "force": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(count); v != 3 {
+		t.Fatalf("expected count 3 but got %v", v)
+	}
+	if bound != 7 {
+		t.Fatalf("expected the bound target to be unchanged, not %d", bound)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	t.Parallel()
+
+	var stdout strings.Builder
+	code := -1
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("tool"),
+		argparse.Version("tool 1.2.3"),
+		argparse.Stdout(&stdout),
+		argparse.Exit(func(c int) { code = c }))
+	p.MustAddArgument(
+		argparse.OptionStrings("-V", "--version"),
+		argparse.ActionFunc(argparse.ShowVersion))
+
+	if _, err := p.ParseArgs("--version"); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "tool 1.2.3\n" || code != 0 {
+		t.Fatalf("unexpected version output %q and exit code %d",
+			stdout.String(), code)
+	}
+
+	q := argparse.MustNewArgumentParser(
+		argparse.Prog("tool"),
+		argparse.Exit(func(c int) { t.Fatalf("unexpected exit: %d", c) }))
+	q.MustAddArgument(
+		argparse.OptionStrings("--version"),
+		argparse.ActionFunc(argparse.ShowVersion))
+	if _, err := q.ParseArgs("--version"); err == nil {
+		t.Fatal("expected an error without a Version")
+	}
+}
+
+func TestParseMapAndJSON(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	count := p.MustAddArgument(
+		argparse.OptionStrings("--count"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+	verbose := p.MustAddArgument(
+		argparse.OptionStrings("--verbose"),
+		argparse.ActionFunc(argparse.StoreTrue))
+	tags := p.MustAddArgument(
+		argparse.OptionStrings("--tag"),
+		argparse.Dest("tags"),
+		argparse.ActionFunc(argparse.Append),
+		argparse.Nargs(1))
+
+	ns, err := p.ParseMap(map[string]string{"count": "2", "verbose": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.MustGet(count) != 2 || ns.MustGet(verbose) != true {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+	if _, err := p.ParseMap(map[string]string{"size": "1"}); err == nil {
+		t.Fatal("expected an error for an unexpected key")
+	}
+
+	ns, err = p.ParseJSON(json.RawMessage(`{"count": null, "other": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ns.Get(count); ok {
+		t.Fatalf("expected a null count to be left out: %v", ns)
+	}
+	if _, err = p.ParseJSON(json.RawMessage(`{"other": 1}`)); err == nil {
+		t.Fatal("expected an error for an unexpected key")
+	}
+	ns, err = p.ParseJSON(json.RawMessage(
+		`{"count": 3, "verbose": false, "tags": ["a", "b"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.MustGet(count) != 3 {
+		t.Fatalf("expected count 3 but got %v", ns.MustGet(count))
+	}
+	if v, ok := ns.Get(verbose); ok && v != false {
+		t.Fatalf("expected verbose to be false but got %v", v)
+	}
+	if ss := ns.MustGetStrings(tags); strings.Join(ss, " ") != "a b" {
+		t.Fatalf("expected tags [a b] but got %v", ss)
+	}
+	for _, bad := range []string{
+		`{"count": {"n": 3}}`,
+		`{"count": "x"}`,
+		`[1, 2]`,
+	} {
+		if _, err := p.ParseJSON(json.RawMessage(bad)); err == nil {
+			t.Fatalf("expected an error from %s", bad)
+		}
+	}
+}
+
+func TestHelpFlagAsValue(t *testing.T) {
+	t.Parallel()
+
+	var stderr strings.Builder
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("grep"),
+		argparse.Stderr(&stderr),
+		argparse.Exit(func(code int) { t.Fatalf("unexpected exit: %d", code) }))
+	pattern := p.MustAddArgument(
+		argparse.OptionStrings("-e", "--regexp"),
+		argparse.Action("store"))
+
+	ns, err := p.ParseArgs("-e", "-h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(pattern); v != "-h" {
+		t.Fatalf("expected the pattern -h but got %v", v)
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("unexpected help output:\n%s", stderr.String())
+	}
+}
+
+// deadlineReader is a pipe that isn't an *os.File so that prompts read from
+// it.
+type deadlineReader struct{ *os.File }
+
+func TestPromptCancelled(t *testing.T) {
+	t.Parallel()
+
+	newParser := func(stdin io.Reader) *argparse.ArgumentParser {
+		p := argparse.MustNewArgumentParser(
+			argparse.Stdin(stdin),
+			argparse.Stderr(io.Discard))
+		p.MustAddArgument(
+			argparse.OptionStrings("--force"),
+			argparse.ActionFunc(argparse.StoreTrue),
+			argparse.Confirm("Really?"))
+		p.MustAddArgument(
+			argparse.OptionStrings("-v"),
+			argparse.ActionFunc(argparse.StoreTrue))
+		return p
+	}
+	cancelled := func(p *argparse.ArgumentParser) {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := p.ParseArgsContext(ctx, "-v"); err != argparse.ErrPromptCancelled {
+			t.Fatalf("expected %v but got %v", argparse.ErrPromptCancelled, err)
+		}
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	p := newParser(deadlineReader{r})
+	cancelled(p)
+	if _, err := io.WriteString(w, "y\nlater\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ParseArgs("-v"); err != nil {
+		t.Fatal(err)
+	}
+	var b [6]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil || string(b[:]) != "later\n" {
+		t.Fatalf("expected the program to read %q but got %q (%v)",
+			"later\n", b[:], err)
+	}
+
+	// reads from readers without deadlines can't be interrupted, so the
+	// line goes to the next prompt.
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	p = newParser(pr)
+	cancelled(p)
+	go io.WriteString(pw, "y\n")
+	if _, err := p.ParseArgs("-v"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnvVar(t *testing.T) {
+	t.Setenv("ARGPARSE_TEST_WORKERS", "6")
+
+	p := argparse.MustNewArgumentParser()
+	workers := p.MustAddArgument(
+		argparse.OptionStrings("--workers"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int),
+		argparse.Default(1),
+		argparse.EnvVar("ARGPARSE_TEST_WORKERS"))
+	p.MustAddArgument(
+		argparse.OptionStrings("-v"),
+		argparse.ActionFunc(argparse.StoreTrue))
+
+	ns, err := p.ParseArgs("-v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(workers); v != 6 {
+		t.Fatalf("expected workers 6 (type: int) from the environment "+
+			"but got %v (type: %T)", v, v)
+	}
+	ns, err = p.ParseArgs("--workers", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(workers); v != 2 {
+		t.Fatalf("expected the command line's workers 2 but got %v", v)
+	}
+	t.Setenv("ARGPARSE_TEST_WORKERS", "many")
+	if _, err = p.ParseArgs("-v"); err == nil {
+		t.Fatal("expected an error from an invalid environment value")
+	}
+}
+
+func TestHelpOrder(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"), argparse.NoHelp)
+	for _, op := range []string{"--zeta", "--alpha", "--mid"} {
+		p.MustAddArgument(
+			argparse.OptionStrings(op),
+			argparse.Action("store"),
+			argparse.Help("The %s option.", op[2:]))
+	}
+	for _, dest := range []string{"source", "dest"} {
+		p.MustAddArgument(
+			argparse.Dest(dest),
+			argparse.Nargs(1),
+			argparse.Help("The %s.", dest))
+	}
+
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := -1
+	for _, s := range []string{
+		"The source.", "The dest.",
+		"The zeta option.", "The alpha option.", "The mid option.",
+	} {
+		i := strings.Index(help, s)
+		if i <= last {
+			t.Fatalf("expected %q after the previous arguments in "+
+				"definition order:\n%s", s, help)
+		}
+		last = i
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	var count, size int
+	var name string
+	p.MustAddArgument(
+		argparse.OptionStrings("--count"),
+		argparse.Action("store")).MustBind(&count)
+	p.MustAddArgument(
+		argparse.OptionStrings("--name"),
+		argparse.Action("store")).MustBind(&name)
+	p.MustAddArgument(
+		argparse.OptionStrings("--size"),
+		argparse.Action("store")).MustBind(&size)
+
+	_, err := p.ParseArgs("--count", "3", "--name", "x", "--size", "4")
+	errs, ok := err.(argparse.BindErrors)
+	if !ok {
+		t.Fatalf("expected BindErrors but got %v (type: %T)", err, err)
+	}
+	if len(errs) != 2 || errs[0].Dest != "count" || errs[1].Dest != "size" {
+		t.Fatalf("expected errors for count and size but got %v", errs)
+	}
+	if errs[0].Value != "3" || errs[0].Target.Kind() != reflect.Int {
+		t.Fatalf("unexpected error details: %+v", errs[0])
+	}
+	if !strings.Contains(err.Error(), "2 bound arguments failed") {
+		t.Fatalf("unexpected message: %v", err)
+	}
+	if name != "x" {
+		t.Fatalf("expected the other targets to be set, not %q", name)
+	}
+}
+
+func TestBindKeepsPresetValue(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	workers := 8
+	p.MustAddArgument(
+		argparse.OptionStrings("--workers"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int)).MustBind(&workers)
+	p.MustAddArgument(
+		argparse.OptionStrings("-v"),
+		argparse.ActionFunc(argparse.StoreTrue))
+
+	if _, err := p.ParseArgs("-v"); err != nil {
+		t.Fatal(err)
+	}
+	if workers != 8 {
+		t.Fatalf("expected the preset value 8 but got %d", workers)
+	}
+	if _, err := p.ParseArgs("--workers", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if workers != 2 {
+		t.Fatalf("expected the parsed value 2 but got %d", workers)
+	}
+}
+
+func TestHidden(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	debug := p.MustAddArgument(
+		argparse.OptionStrings("--debug-internals"),
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.Hidden,
+		argparse.Help("Dump internal state."))
+	p.MustAddArgument(
+		argparse.OptionStrings("--verbose"),
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.Help("Verbose output."))
+
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(help, "--debug-internals") || strings.Contains(help, "internal state") {
+		t.Fatalf("unexpected hidden argument in help:\n%s", help)
+	}
+	if !strings.Contains(help, "--verbose") {
+		t.Fatalf("expected --verbose in help:\n%s", help)
+	}
+	ns, err := p.ParseArgs("--debug-internals")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(debug); v != true {
+		t.Fatalf("expected the hidden argument to parse but got %v", v)
+	}
+}
+
+func TestBindField(t *testing.T) {
+	t.Parallel()
+
+	type tls struct {
+		Cert string
+	}
+	type server struct {
+		Port int
+		TLS  *tls
+	}
+	var config struct {
+		Server server
+	}
+	p := argparse.MustNewArgumentParser()
+	p.MustAddArgument(
+		argparse.OptionStrings("--port"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int)).MustBindField(&config, "Server.Port")
+	p.MustAddArgument(
+		argparse.OptionStrings("--cert"),
+		argparse.Action("store")).MustBindField(&config, "Server.TLS.Cert")
+
+	if config.Server.TLS == nil {
+		t.Fatal("expected the nil *tls along the path to be allocated")
+	}
+	if _, err := p.ParseArgs("--port", "8443", "--cert", "server.pem"); err != nil {
+		t.Fatal(err)
+	}
+	if config.Server.Port != 8443 || config.Server.TLS.Cert != "server.pem" {
+		t.Fatalf("unexpected config: %+v, %+v", config.Server, *config.Server.TLS)
+	}
+
+	a := p.MustAddArgument(
+		argparse.OptionStrings("--other"),
+		argparse.Action("store"))
+	for _, path := range []string{"Server.Missing", "Server.Port.Value"} {
+		if err := a.BindField(&config, path); err == nil {
+			t.Fatalf("expected an error binding %q", path)
+		}
+	}
+}
+
+func TestBindErrorElement(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser()
+	var ports []int
+	p.MustAddArgument(
+		argparse.OptionStrings("--port"),
+		argparse.ActionFunc(argparse.Append),
+		argparse.Nargs(1)).MustBind(&ports)
+
+	_, err := p.ParseArgs("--port", "80", "--port", "443")
+	if err == nil {
+		t.Fatal("expected an error binding strings to []int")
+	}
+	for _, expect := range []string{
+		"cannot assign element 0 (type: string) of []interface {} to element of []int",
+		"(hint: use argparse.Type(argparse.Int) to parse values as int)",
+	} {
+		if !strings.Contains(err.Error(), expect) {
+			t.Fatalf("expected %q in:\n%v", expect, err)
+		}
+	}
+}
+
+func TestStrictSubparsers(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("tool"), argparse.Strict)
+	sub, err := p.AddSubparsers(argparse.Dest("command"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := sub.MustAddParser("build", argparse.Strict)
+	jobs := build.MustAddArgument(
+		argparse.OptionStrings("-j"),
+		argparse.Action("store"),
+		argparse.Type(argparse.Int))
+
+	ns, err := p.ParseArgs("build", "-j", "4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns["command"] != "build" || ns.MustGet(jobs) != 4 {
+		t.Fatalf("unexpected namespace: %v", ns)
+	}
+}
+
+func TestPersistentAccumulates(t *testing.T) {
+	t.Parallel()
+
+	for _, nested := range []bool{false, true} {
+		p := argparse.MustNewArgumentParser(argparse.Prog("tool"))
+		p.NestedNamespaces = nested
+		tags := p.MustAddArgument(
+			argparse.OptionStrings("-t", "--tag"),
+			argparse.ActionFunc(argparse.Append),
+			argparse.Nargs(1),
+			argparse.Persistent)
+		p.MustAddSubparsers().MustAddParser("build")
+
+		ns, err := p.ParseArgs("-t", "a", "build", "-t", "b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ss := ns.MustGetStrings(tags)
+		if len(ss) != 2 || ss[0] != "a" || ss[1] != "b" {
+			t.Fatalf("nested: %v: expected [a b] but got %v",
+				nested, ss)
+		}
+	}
+}
+
+func TestHelpOptionStrings(t *testing.T) {
+	t.Parallel()
+
+	var stderr strings.Builder
+	code := -1
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.HelpOptionStrings("-?", "--usage"),
+		argparse.Stderr(&stderr),
+		argparse.Exit(func(c int) { code = c }))
+	host := p.MustAddArgument(
+		argparse.OptionStrings("-h", "--host"),
+		argparse.Action("store"))
+
+	ns, err := p.ParseArgs("-h", "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(host); v != "example.com" {
+		t.Fatalf("expected %q but got %v", "example.com", v)
+	}
+	if code != -1 || stderr.Len() != 0 {
+		t.Fatalf("unexpected help %q with exit code %d", stderr.String(), code)
+	}
+	if _, err := p.ParseArgs("--usage"); err != nil {
+		t.Fatal(err)
+	}
+	if code == -1 || !strings.Contains(stderr.String(), "-?, --usage") {
+		t.Fatalf("expected help with the custom option strings but got %q", stderr.String())
+	}
+}
+
+func TestHelpStdout(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr strings.Builder
+	code := -1
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("test"),
+		argparse.HelpStdout,
+		argparse.Stdout(&stdout),
+		argparse.Stderr(&stderr),
+		argparse.Exit(func(c int) { code = c }))
+
+	if _, err := p.ParseArgs("--help"); err != nil {
+		t.Fatal(err)
+	}
+	if code != 0 {
+		t.Fatalf("expected exit code 0 but got %d", code)
+	}
+	if !strings.HasPrefix(stdout.String(), "usage: test") || stderr.Len() != 0 {
+		t.Fatalf("expected help on stdout but got stdout:\n%s\nstderr:\n%s",
+			stdout.String(), stderr.String())
+	}
+}
+
+func TestDefaultFunc(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	p := argparse.MustNewArgumentParser()
+	dir := p.MustAddArgument(
+		argparse.OptionStrings("--dir"),
+		argparse.Action("store"),
+		argparse.DefaultFunc(func() (interface{}, error) {
+			calls++
+			return "cwd", nil
+		}))
+	p.MustAddArgument(
+		argparse.OptionStrings("-v"),
+		argparse.ActionFunc(argparse.StoreTrue))
+
+	ns, err := p.ParseArgs("--dir", "tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Fatalf("expected DefaultFunc not to be called but it was called %d times", calls)
+	}
+	if v := ns.MustGet(dir); v != "tmp" {
+		t.Fatalf("expected %q but got %v", "tmp", v)
+	}
+	if ns, err = p.ParseArgs("-v"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected DefaultFunc to be called once but it was called %d times", calls)
+	}
+	if v := ns.MustGet(dir); v != "cwd" {
+		t.Fatalf("expected %q but got %v", "cwd", v)
+	}
+}
+
+func TestSuppress(t *testing.T) {
+	t.Parallel()
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	name := p.MustAddArgument(
+		argparse.OptionStrings("--name"),
+		argparse.Action("store"),
+		argparse.Default(argparse.Suppress))
+	p.MustAddArgument(
+		argparse.OptionStrings("--debug-internals"),
+		argparse.ActionFunc(argparse.StoreTrue),
+		argparse.Help(argparse.Suppress))
+
+	ns, err := p.ParseArgs("--debug-internals")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := ns.Get(name); ok {
+		t.Fatalf("expected %q to be absent but got %v", name.Dest, v)
+	}
+	if ns, err = p.ParseArgs("--name", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if v := ns.MustGet(name); v != "x" {
+		t.Fatalf("expected %q but got %v", "x", v)
+	}
+	help, err := p.FormatHelp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(help, "--name") || strings.Contains(help, "--debug-internals") {
+		t.Fatalf("expected only --debug-internals to be hidden in:\n%s", help)
+	}
+}
+
+func TestSubparserInheritsSettings(t *testing.T) {
+	t.Parallel()
+
+	var out strings.Builder
+	code := -1
+	p := argparse.MustNewArgumentParser(
+		argparse.Prog("tool"),
+		argparse.Strict,
+		argparse.Exit(func(c int) { code = c }),
+		argparse.Stdout(&out),
+		argparse.Stderr(&out))
+	sub, err := p.AddSubparsers(argparse.Dest("command"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := sub.MustAddParser("build")
+
+	if _, err := p.ParseArgs("build", "-h"); err != nil {
+		t.Fatal(err)
+	}
+	if code == -1 {
+		t.Fatal("expected the root parser's Exit to be called")
+	}
+	if !strings.HasPrefix(out.String(), "usage: tool build") {
+		t.Fatalf("expected the sub-command's help but got:\n%s", out.String())
+	}
+	if _, err := build.AddArgument(
+		argparse.OptionStrings("-j"),
+		argparse.Action("store")); err == nil {
+		t.Fatal("expected the sub-parser to be Strict")
+	}
+}
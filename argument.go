@@ -1,8 +1,11 @@
 package argparse
 
 import (
+	"flag"
 	"fmt"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/skillian/errors"
@@ -26,12 +29,46 @@ type Argument struct {
 	// value is not otherwise provided.
 	Default interface{}
 
+	// DefaultFunc gets the default value of the argument if Default is
+	// nil.  It is only called when the argument is missing, so it can be
+	// used for defaults that are expensive or context-dependent to
+	// determine.
+	DefaultFunc func() (interface{}, error)
+
 	// Dest is the string key that the argument can be retrieved by.
 	Dest string
 
 	// Help is the help text associated with the argument.
 	Help string
 
+	// Hidden arguments are parsed but aren't shown in the help, usage or
+	// completions.
+	Hidden bool
+
+	// MinOccurrences and MaxOccurrences limit the number of times an
+	// optional argument can be given (e.g. with the Append action).
+	// A MaxOccurrences of 0 means there is no limit.
+	MinOccurrences int
+	MaxOccurrences int
+
+	// Persistent optional arguments can also be given after the name of
+	// any of their parser's sub-commands (e.g. both "tool --verbose
+	// build" and "tool build --verbose").
+	Persistent bool
+
+	// Secret arguments' values (e.g. passwords and tokens) are redacted
+	// when the command line is rendered with Namespace's
+	// QuotedCommandLine.
+	Secret bool
+
+	// Deprecated is a message explaining what to use instead of the
+	// argument.  Using a deprecated argument writes a warning.
+	Deprecated string
+
+	// RemovedIn is the version of the program that the deprecated
+	// argument will be removed in (e.g. "v2.0").
+	RemovedIn string
+
 	// MetaVar is the variable that the argument is represented with when
 	// displaying its usage.  It is a slice in case Nargs is non-zero.
 	MetaVar []string
@@ -48,6 +85,11 @@ type Argument struct {
 	// Required determines if the argument is required or not.
 	Required bool
 
+	// RequiredIf makes the argument required only when the predicate
+	// returns true.  The predicate is called with the namespace after
+	// defaults are applied to the other arguments.
+	RequiredIf func(ns Namespace) bool
+
 	// Type holds a function that can be used to parse a string value into
 	// the type desired by this argument.
 	Type ValueParser
@@ -55,9 +97,76 @@ type Argument struct {
 	// Choices holds an optional collection of allowed choices for this
 	// Argument.  Choices is nil if no set of allowed values was provided.
 	Choices *ArgumentChoices
+
+	// EnvVar is the name of an environment variable that the argument's
+	// value is read from when the argument isn't given on the command
+	// line.
+	EnvVar string
+
+	// Confirm is a prompt that the user must confirm when the argument
+	// isn't given on the command line.  Confirming the prompt is the same
+	// as giving the argument.  It is meant for flags like --force that
+	// guard dangerous operations.
+	Confirm string
+
+	// Clamp snaps out-of-range values of range-constrained types to the
+	// nearest bound (with a warning) instead of failing to parse.
+	Clamp bool
+
+	// negations are the "--no-" option strings generated for a
+	// BooleanOptional argument.
+	negations []string
+
+	// typedSlice is set by ElemType so that the argument's values are
+	// stored in a slice of their type instead of a []interface{}.
+	typedSlice bool
+
+	// transforms change the argument's strings before they're parsed.
+	transforms []func(v string) string
+
+	// validators check the argument's values after they're parsed.
+	validators []func(v interface{}) error
+
+	// typ is the type of the values that Type produces when it's known
+	// from how Type was set (e.g. with TypeFunc).
+	typ reflect.Type
+
+	// expandGlobs is set by ExpandGlobs so that the paths that each value
+	// matches are stored as separate values.
+	expandGlobs bool
+
+	// renames are the argument's old option strings that are still
+	// accepted (with a warning) after the argument was renamed.
+	renames []string
+
+	// constraints hold descriptions of the restrictions on the argument's
+	// values (e.g. "1-65535") that are enforced during parsing.  They are
+	// appended to the argument's help so that the documentation cannot
+	// drift from what's actually enforced.
+	constraints []string
+
+	// suggestions are well-known values of the argument that are
+	// completed and listed in the help like Choices, but unlike Choices,
+	// other values are allowed.
+	suggestions []Choice
+
+	// subparsers is set when the argument's values are parsed by
+	// sub-parsers.
+	subparsers *Subparsers
+
+	// group is the argument group the argument was added to, if any.
+	group *ArgumentGroup
+
+	// requires and conflicts are the arguments that must or must not be
+	// given when this argument is given.
+	requires  []*Argument
+	conflicts []*Argument
 }
 
-// Bind the argument's parsed value into the given pointer.
+// Bind the argument's parsed value into the given pointer.  The target can
+// also be a func(T) or func(T) error that's called with the value converted
+// to T after parsing, e.g. to set a log level.  Errors returned from the
+// function fail the parse.
 func (a *Argument) Bind(target interface{}) error {
 	return a.parser.boundArgs.bind(a, target)
 }
@@ -69,6 +178,109 @@ func (a *Argument) MustBind(target interface{}) {
 	}
 }
 
+// BindField binds the argument's parsed value into a field of the struct
+// that target points to.  The path is a dot-separated path of field names
+// so that fields of nested structs (e.g. "Server.Port") can be bound.
+func (a *Argument) BindField(target interface{}, path string) error {
+	return a.parser.boundArgs.bindField(a, target, path)
+}
+
+// MustBindField panics if binding an argument to a field fails.
+func (a *Argument) MustBindField(target interface{}, path string) {
+	if err := a.BindField(target, path); err != nil {
+		panic(err)
+	}
+}
+
+// suggest adds well-known values of the argument to its completions and
+// help.
+func (a *Argument) suggest(choices ...Choice) {
+	a.suggestions = append(a.suggestions, choices...)
+	a.addConstraint("e.g. %s", strings.Join(keysOf(choices), ", "))
+}
+
+// addConstraint records a description of a restriction enforced on the
+// argument's values so that it is shown in the argument's help.
+func (a *Argument) addConstraint(format string, args ...interface{}) {
+	a.constraints = append(a.constraints, fmt.Sprintf(format, args...))
+}
+
+// limits describes the limits on the number of the argument's values and
+// occurrences for its help, e.g. "up to 4 values".  They're described when
+// the help is formatted and not by the options that set them because
+// whether an occurrence limits the number of values depends on the Action,
+// which can be set by a later option.
+func (a *Argument) limits() (notes []string) {
+	switch {
+	case a.Nargs < 1:
+	case a.Action == Append || a.Action == Extend:
+		if a.MinOccurrences > 0 {
+			notes = append(notes, fmt.Sprintf(
+				"at least %d values", a.MinOccurrences*a.Nargs))
+		}
+		if a.MaxOccurrences > 0 {
+			notes = append(notes, fmt.Sprintf(
+				"up to %d values", a.MaxOccurrences*a.Nargs))
+		}
+		return
+	case a.Nargs > 1:
+		notes = append(notes, fmt.Sprintf("%d values", a.Nargs))
+	}
+	if a.MinOccurrences > 0 {
+		notes = append(notes, fmt.Sprintf(
+			"at least %d times", a.MinOccurrences))
+	}
+	if a.MaxOccurrences > 0 {
+		notes = append(notes, fmt.Sprintf(
+			"at most %d times", a.MaxOccurrences))
+	}
+	return
+}
+
+// RangeError is returned by range-constrained Types when a value is outside
+// of their range.  Arguments with the Clamp option use the Nearest bound in
+// place of the Value instead of failing.
+type RangeError struct {
+	// Value is the out-of-range value.
+	Value interface{}
+
+	// Nearest is the bound of the range nearest to Value.
+	Nearest interface{}
+
+	// Range describes the range, e.g. "[1, 8]".
+	Range string
+}
+
+// Error implements the error interface.
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("value %v is out of range %s", e.Value, e.Range)
+}
+
+// parseValue parses v with the argument's Type.  If v is out of the Type's
+// range and the argument is clamped, a warning is written and the nearest
+// bound is returned instead of the error.
+func (a *Argument) parseValue(v string) (interface{}, error) {
+	x, err := a.Type(v)
+	if re, ok := err.(*RangeError); ok && a.Clamp {
+		a.parser.warnf(
+			"value %v of argument %q is out of range %s; using %v",
+			re.Value, a.Dest, re.Range, re.Nearest)
+		return re.Nearest, nil
+	}
+	return x, err
+}
+
+// negation returns true if the option string is one of the argument's
+// generated "--no-" option strings.
+func (a *Argument) negation(op string) bool {
+	for _, n := range a.negations {
+		if n == op {
+			return true
+		}
+	}
+	return false
+}
+
 // Optional returns whether or not this is an optional (flag) argument.  If
 // it is not, then it is a positional argument.
 func (a *Argument) Optional() bool {
@@ -90,123 +302,207 @@ const (
 
 	// ZeroOrOne indicates that zero or one argument is allowed
 	ZeroOrOne
+
+	// Parser indicates that the argument consumes its first value and
+	// every argument after it, even if they look like options.  Only the
+	// first value is converted by the argument's Type; the rest are kept
+	// untouched as strings so they can be handed off to another program
+	// (e.g. git-style dispatch to external executables).
+	Parser
 )
 
+// Suppress can be used as an argument's Default so that the argument's Dest
+// isn't added to the Namespace at all when the argument isn't given, or as
+// an argument's Help to hide the argument from the help output.
+const Suppress = "==SUPPRESS=="
+
 // isValidNarg is a helper function that can tell if a Nargs value is either a
 // valid number of arguments or valid sentinel value.
 func isValidNarg(v int) bool {
-	return v >= ZeroOrOne
+	return v >= Parser
 }
 
 // ValueParser can parse a string value into a Go value.
 type ValueParser func(v string) (interface{}, error)
 
-// Bool converts the given string into a boolean value.
+// Bool converts the given string into a boolean value.  Case-insensitively,
+// "true", "t", "yes", "y", "on" and "1" are true and "false", "f", "no",
+// "n", "off" and "0" are false.
 // It implements the ValueParser interface.
 func Bool(v string) (interface{}, error) {
-	if strings.EqualFold(v, "true") {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true", "t", "yes", "y", "on", "1":
 		return true, nil
-	}
-	if strings.EqualFold(v, "false") {
+	case "false", "f", "no", "n", "off", "0":
 		return false, nil
 	}
-	return nil, errors.NewUnexpectedType(false, v)
+	return nil, errors.Errorf("invalid bool: %q", v)
 }
 
 // Float32 converts the given string into a float32 value.
 // It implements the ValueParser interface.
 func Float32(v string) (interface{}, error) {
-	var f float32
-	err := sscanf(v, "%f", &f)
-	return f, err
+	f, err := strconv.ParseFloat(v, 32)
+	if err != nil {
+		return nil, numError(err, "float32", v)
+	}
+	return float32(f), nil
 }
 
 // Float64 converts the given string into a float64 value.
 // It implements the ValueParser interface.
 func Float64(v string) (interface{}, error) {
-	var f float64
-	err := sscanf(v, "%f", &f)
-	return f, err
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, numError(err, "float64", v)
+	}
+	return f, nil
 }
 
-// Int converts the given string into a int value.
+// Int converts the given string into a int value.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but
+// a leading 0 without a prefix is decimal, not octal.
 // It implements the ValueParser interface.
 func Int(v string) (interface{}, error) {
-	var i int
-	err := sscanf(v, "%d", &i)
-	return i, err
+	i, err := strconv.ParseInt(v, intBase(v), strconv.IntSize)
+	if err != nil {
+		return nil, numError(err, "int", v)
+	}
+	return int(i), nil
 }
 
-// Int8 converts the given string into a int8 value.
+// Int8 converts the given string into a int8 value.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but
+// a leading 0 without a prefix is decimal, not octal.
 // It implements the ValueParser interface.
 func Int8(v string) (interface{}, error) {
-	var i int8
-	err := sscanf(v, "%d", &i)
-	return i, err
+	i, err := strconv.ParseInt(v, intBase(v), 8)
+	if err != nil {
+		return nil, numError(err, "int8", v)
+	}
+	return int8(i), nil
 }
 
-// Int16 converts the given string into a int16 value.
+// Int16 converts the given string into a int16 value.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but
+// a leading 0 without a prefix is decimal, not octal.
 // It implements the ValueParser interface.
 func Int16(v string) (interface{}, error) {
-	var i int16
-	err := sscanf(v, "%d", &i)
-	return i, err
+	i, err := strconv.ParseInt(v, intBase(v), 16)
+	if err != nil {
+		return nil, numError(err, "int16", v)
+	}
+	return int16(i), nil
 }
 
-// Int32 converts the given string into a int32 value.
+// Int32 converts the given string into a int32 value.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but
+// a leading 0 without a prefix is decimal, not octal.
 // It implements the ValueParser interface.
 func Int32(v string) (interface{}, error) {
-	var i int32
-	err := sscanf(v, "%d", &i)
-	return i, err
+	i, err := strconv.ParseInt(v, intBase(v), 32)
+	if err != nil {
+		return nil, numError(err, "int32", v)
+	}
+	return int32(i), nil
 }
 
-// Int64 converts the given string into a int value.
+// Int64 converts the given string into a int64 value.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but
+// a leading 0 without a prefix is decimal, not octal.
 // It implements the ValueParser interface.
 func Int64(v string) (interface{}, error) {
-	var i int64
-	err := sscanf(v, "%d", &i)
-	return i, err
+	i, err := strconv.ParseInt(v, intBase(v), 64)
+	if err != nil {
+		return nil, numError(err, "int64", v)
+	}
+	return i, nil
 }
 
-// Uint converts the given string into a uint value.
+// Uint converts the given string into a uint value.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but
+// a leading 0 without a prefix is decimal, not octal.
 // It implements the ValueParser interface.
 func Uint(v string) (interface{}, error) {
-	var i uint
-	err := sscanf(v, "%u", &i)
-	return i, err
+	i, err := strconv.ParseUint(v, intBase(v), strconv.IntSize)
+	if err != nil {
+		return nil, numError(err, "uint", v)
+	}
+	return uint(i), nil
 }
 
-// Uint8 converts the given string into a uint8 value.
+// Uint8 converts the given string into a uint8 value.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but
+// a leading 0 without a prefix is decimal, not octal.
 // It implements the ValueParser interface.
 func Uint8(v string) (interface{}, error) {
-	var i uint8
-	err := sscanf(v, "%u", &i)
-	return i, err
+	i, err := strconv.ParseUint(v, intBase(v), 8)
+	if err != nil {
+		return nil, numError(err, "uint8", v)
+	}
+	return uint8(i), nil
 }
 
-// Uint16 converts the given string into a uint16 value.
+// Uint16 converts the given string into a uint16 value.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but
+// a leading 0 without a prefix is decimal, not octal.
 // It implements the ValueParser interface.
 func Uint16(v string) (interface{}, error) {
-	var i uint16
-	err := sscanf(v, "%u", &i)
-	return i, err
+	i, err := strconv.ParseUint(v, intBase(v), 16)
+	if err != nil {
+		return nil, numError(err, "uint16", v)
+	}
+	return uint16(i), nil
 }
 
-// Uint32 converts the given string into a uint32 value.
+// Uint32 converts the given string into a uint32 value.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but
+// a leading 0 without a prefix is decimal, not octal.
 // It implements the ValueParser interface.
 func Uint32(v string) (interface{}, error) {
-	var i uint32
-	err := sscanf(v, "%u", &i)
-	return i, err
+	i, err := strconv.ParseUint(v, intBase(v), 32)
+	if err != nil {
+		return nil, numError(err, "uint32", v)
+	}
+	return uint32(i), nil
 }
 
-// Uint64 converts the given string into a uint64 value.
+// Uint64 converts the given string into a uint64 value.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but
+// a leading 0 without a prefix is decimal, not octal.
 // It implements the ValueParser interface.
 func Uint64(v string) (interface{}, error) {
-	var i uint64
-	err := sscanf(v, "%u", &i)
-	return i, err
+	i, err := strconv.ParseUint(v, intBase(v), 64)
+	if err != nil {
+		return nil, numError(err, "uint64", v)
+	}
+	return i, nil
+}
+
+// intBase gets the base that strconv should parse the integer v in.  Like
+// Go integer literals, v's base is given by its 0b, 0o or 0x prefix (and
+// base 0 allows underscores), but unlike them, a leading 0 without a prefix
+// doesn't make v octal, so "010" is 10.
+func intBase(v string) int {
+	if len(v) > 0 && (v[0] == '+' || v[0] == '-') {
+		v = v[1:]
+	}
+	if len(v) < 2 || v[0] != '0' {
+		return 0
+	}
+	switch v[1] {
+	case 'b', 'B', 'o', 'O', 'x', 'X':
+		return 0
+	}
+	return 10
+}
+
+// numError describes why v couldn't be parsed as a number of the given type.
+func numError(err error, typ, v string) error {
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		return errors.Errorf("%q is out of range for %s", v, typ)
+	}
+	return errors.ErrorfWithCause(err, "invalid %s: %q", typ, v)
 }
 
 // String is a "dummy" ValueParser filled in automatically by AddArgument if
@@ -215,17 +511,6 @@ func String(v string) (interface{}, error) {
 	return v, nil
 }
 
-func sscanf(s, f string, p interface{}) error {
-	n, err := fmt.Sscanf(s, f, p)
-	if err != nil {
-		return err
-	}
-	if n != 1 {
-		return errors.Errorf("%d != 1", n)
-	}
-	return nil
-}
-
 // Action takes the name of an action instead of the action function.
 // it works similarly to Python's argparse.ArgumentParser.add_argument's
 // action parameter when set to a string value.
@@ -248,10 +533,19 @@ func ActionFunc(f ArgumentAction) ArgumentOption {
 	return func(a *Argument) error {
 		a.Action = f
 		switch f {
-		case Store:
+		case Store, Overwrite, StoreMap:
 			if a.Nargs < 1 {
 				a.Nargs = 1
 			}
+		case BooleanOptional:
+			a.Const = true
+			a.Nargs = 0
+		case AppendConst, ShowHelp, ShowVersion:
+			a.Nargs = 0
+		case Extend:
+			if a.Nargs == 0 {
+				a.Nargs = OneOrMore
+			}
 		case StoreTrue:
 			a.Default = false
 			a.Const = true
@@ -310,6 +604,23 @@ var (
 		},
 	)
 
+	// AppendConst is an ArgumentAction that appends the argument's Const
+	// value to the values associated with the argument's Dest.  Multiple
+	// arguments can share the same Dest to append their Const values into
+	// the same slice.
+	AppendConst ArgumentAction = newArgumentActionStruct(
+		"append_const",
+		func(a *Argument, ns Namespace, args []interface{}) error {
+			if len(args) != 1 {
+				return errors.Errorf(
+					"one value expected for argument %q but got %d: %#v",
+					a.Dest, len(args), args)
+			}
+			ns.Append(a, args[0])
+			return nil
+		},
+	)
+
 	// Store is an ArgumentAction that sets the value associated with the
 	// given argument.  If that argument already has a value in the given
 	// namespace, an error is returned.
@@ -330,6 +641,135 @@ var (
 		},
 	)
 
+	// BooleanOptional is an ArgumentAction that stores true when any of
+	// the argument's option strings is given.  For each "--" option
+	// string, a "--no-" option string is added to the argument that
+	// stores false when given, so the argument's default can be
+	// overridden in either direction.
+	BooleanOptional ArgumentAction = newArgumentActionStruct(
+		"boolean_optional",
+		func(a *Argument, ns Namespace, args []interface{}) error {
+			if len(args) != 1 {
+				return errors.Errorf(
+					"one value expected for argument %q but got %d: %#v",
+					a.Dest, len(args), args)
+			}
+			ns.Set(a, args[0])
+			return nil
+		},
+	)
+
+	// Extend is an ArgumentAction that appends each of an encountered
+	// argument's values to the values associated with the argument so that
+	// multiple occurrences produce one flat slice of values instead of a
+	// slice of slices.
+	Extend ArgumentAction = newArgumentActionStruct(
+		"extend",
+		func(a *Argument, ns Namespace, args []interface{}) error {
+			vs, err := a.defaultCreateValues(args)
+			if err != nil {
+				return err
+			}
+			ns.Append(a, vs...)
+			return nil
+		},
+	)
+
+	// StoreMap is an ArgumentAction that parses "key=value" values into
+	// a map[string]interface{} associated with the argument so that
+	// repeated occurrences (e.g. --label a=1 --label b=2) build up one
+	// map.  The argument's Type (or Choices) is applied to the values, not
+	// the keys.  If a key is given more than once, the last value wins.
+	StoreMap ArgumentAction = newArgumentActionStruct(
+		"store_map",
+		func(a *Argument, ns Namespace, args []interface{}) error {
+			m, _ := ns[a.Dest].(map[string]interface{})
+			if m == nil {
+				m = make(map[string]interface{}, len(args))
+			}
+			for _, arg := range args {
+				if def, ok := arg.(map[string]interface{}); ok {
+					for k, v := range def {
+						m[k] = v
+					}
+					continue
+				}
+				kv := stringOf(arg)
+				i := strings.IndexByte(kv, '=')
+				if i < 1 {
+					return errors.Errorf(
+						"value %q of argument %q is not "+
+							"of the form key=value",
+						kv, a.Dest)
+				}
+				vs, err := a.defaultCreateValues(
+					[]interface{}{kv[i+1:]})
+				if err != nil {
+					return err
+				}
+				m[kv[:i]] = vs[0]
+			}
+			ns.Set(a, m)
+			return nil
+		},
+	)
+
+	// Overwrite is an ArgumentAction that sets the value associated with
+	// the given argument like Store, but if the argument already has a
+	// value in the namespace, it is replaced so that the last occurrence
+	// of the argument wins.
+	Overwrite ArgumentAction = newArgumentActionStruct(
+		"overwrite",
+		func(a *Argument, ns Namespace, args []interface{}) error {
+			vs, err := a.defaultCreateValues(args)
+			if err != nil {
+				return err
+			}
+			ns.Set(a, getArgValueForNS(a, vs))
+			return nil
+		},
+	)
+
+	// ShowHelp is an ArgumentAction that writes the parser's help to its
+	// Stderr (or Stdout if HelpStdout is set) and then exits through the
+	// parser's Exit function.
+	ShowHelp ArgumentAction = newArgumentActionStruct(
+		"help",
+		func(a *Argument, ns Namespace, args []interface{}) error {
+			p := a.parser
+			v, err := p.FormatHelp()
+			if err != nil {
+				return err
+			}
+			w, code := p.stderr(), 1
+			if p.HelpStdout {
+				w, code = p.stdout(), 0
+			}
+			if pal, ok := p.helpPalette(w); ok {
+				v = styleHelp(v, pal)
+			}
+			fmt.Fprintln(w, v)
+			p.exit(code)
+			return nil
+		},
+	)
+
+	// ShowVersion is an ArgumentAction that writes the parser's Version
+	// to its Stdout and then exits through the parser's Exit function.
+	ShowVersion ArgumentAction = newArgumentActionStruct(
+		"version",
+		func(a *Argument, ns Namespace, args []interface{}) error {
+			p := a.parser
+			if p.Version == "" {
+				return errors.Errorf(
+					"no version defined for %v", p.Prog)
+			}
+			fmt.Fprintln(p.stdout(), p.Version)
+			p.exit(0)
+			return nil
+		},
+	)
+
 	// StoreTrue is an ArgumentAction that stores the true value in the
 	// given namespace for the given argument.
 	StoreTrue ArgumentAction = newArgumentActionStruct(
@@ -387,6 +827,15 @@ func ChoiceValues(values ...interface{}) ArgumentOption {
 	}
 }
 
+// Confirm sets the prompt the user must confirm if the argument isn't
+// given on the command line.  If the program isn't being run interactively,
+// parsing fails instead.
+func Confirm(prompt string) ArgumentOption {
+	return func(a *Argument) error {
+		return setValue(&a.Confirm, "Confirm", prompt)
+	}
+}
+
 // Const sets the Const value for the given string
 func Const(v interface{}) ArgumentOption {
 	return func(a *Argument) error {
@@ -401,6 +850,15 @@ func Default(v interface{}) ArgumentOption {
 	}
 }
 
+// DefaultFunc sets the function that gets the default value of an argument
+// when the argument is missing.
+func DefaultFunc(f func() (interface{}, error)) ArgumentOption {
+	return func(a *Argument) error {
+		a.DefaultFunc = f
+		return nil
+	}
+}
+
 // Dest sets the destination name in the parsed argument namespace.
 func Dest(v string) ArgumentOption {
 	return func(a *Argument) error {
@@ -408,6 +866,18 @@ func Dest(v string) ArgumentOption {
 	}
 }
 
+// EnvVar sets the name of the environment variable that the argument's value
+// is read from if the argument isn't given on the command line.  The value
+// goes through the argument's Type like a value from the command line.  If
+// the argument takes no values, the environment variable gives the argument
+// unless its value parses as false.  If the argument takes more than one
+// value, the values are separated by whitespace.
+func EnvVar(name string) ArgumentOption {
+	return func(a *Argument) error {
+		return setValue(&a.EnvVar, "EnvVar", name)
+	}
+}
+
 // Help sets the help string of an argument.
 func Help(format string, args ...interface{}) ArgumentOption {
 	v := format
@@ -419,6 +889,214 @@ func Help(format string, args ...interface{}) ArgumentOption {
 	}
 }
 
+// Hidden hides the Argument from the help, usage and completions.
+func Hidden(a *Argument) error {
+	a.Hidden = true
+	return nil
+}
+
+// MinOccurrences sets the minimum number of times that the Argument must be
+// given.
+func MinOccurrences(n int) ArgumentOption {
+	return func(a *Argument) error {
+		if n < 0 {
+			return errors.Errorf("invalid minimum occurrences: %d", n)
+		}
+		return setValue(&a.MinOccurrences, "MinOccurrences", n)
+	}
+}
+
+// MaxOccurrences sets the maximum number of times that the Argument may be
+// given.
+func MaxOccurrences(n int) ArgumentOption {
+	return func(a *Argument) error {
+		if n < 1 {
+			return errors.Errorf("invalid maximum occurrences: %d", n)
+		}
+		return setValue(&a.MaxOccurrences, "MaxOccurrences", n)
+	}
+}
+
+// RequiredIf sets the predicate that determines if the argument is
+// required.
+func RequiredIf(f func(ns Namespace) bool) ArgumentOption {
+	return func(a *Argument) error {
+		if a.RequiredIf != nil {
+			return errors.Errorf("RequiredIf already set!")
+		}
+		a.RequiredIf = f
+		return nil
+	}
+}
+
+// Requires makes the other arguments required when the Argument is given.
+func Requires(others ...*Argument) ArgumentOption {
+	return func(a *Argument) error {
+		for _, o := range others {
+			a.requires = append(a.requires, o)
+			a.addConstraint("requires %s", o.usageName())
+		}
+		return nil
+	}
+}
+
+// ConflictsWith makes it an error to give the Argument along with any of the
+// other arguments.
+func ConflictsWith(others ...*Argument) ArgumentOption {
+	return func(a *Argument) error {
+		for _, o := range others {
+			a.conflicts = append(a.conflicts, o)
+			a.addConstraint("not allowed with %s", o.usageName())
+		}
+		return nil
+	}
+}
+
+// usageName gets the name of the argument as it's shown in the usage: its
+// option strings or, for a positional argument, its metavar.
+func (a *Argument) usageName() string {
+	if a.Optional() {
+		return strings.Join(a.OptionStrings, "/")
+	}
+	if len(a.MetaVar) > 0 {
+		return a.MetaVar[0]
+	}
+	return a.Dest
+}
+
+// Persistent makes the Argument parseable after the names of its parser's
+// sub-commands.
+func Persistent(a *Argument) error {
+	a.Persistent = true
+	return nil
+}
+
+// Secret marks the Argument's values as secret so that they are redacted.
+func Secret(a *Argument) error {
+	a.Secret = true
+	return nil
+}
+
+// Deprecated marks the argument as deprecated with a message explaining what
+// to use instead.
+func Deprecated(v string) ArgumentOption {
+	return func(a *Argument) error {
+		return setValue(&a.Deprecated, "Deprecated", v)
+	}
+}
+
+// RemovedIn sets the version that the deprecated argument will be removed
+// in.
+func RemovedIn(v string) ArgumentOption {
+	return func(a *Argument) error {
+		return setValue(&a.RemovedIn, "RemovedIn", v)
+	}
+}
+
+// FlagValue sets the argument's Type to call the standard library flag.Value's
+// Set method with each of the argument's values so that custom flag types
+// can be reused.  The flag.Value itself is stored in the namespace.  Like
+// with the flag package, every occurrence of the argument calls Set and
+// bool flags (whose IsBoolFlag method returns true) don't take a value.
+func FlagValue(v flag.Value) ArgumentOption {
+	return func(a *Argument) error {
+		err := Type(func(s string) (interface{}, error) {
+			if err := v.Set(s); err != nil {
+				return nil, err
+			}
+			return v, nil
+		})(a)
+		if err != nil {
+			return err
+		}
+		a.Action = Overwrite
+		a.Nargs = 1
+		if bf, ok := v.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+			a.Nargs = 0
+			a.Const = "true"
+		}
+		return nil
+	}
+}
+
+// ElemType sets the argument's Type and stores the argument's values in the
+// namespace in a slice of the type that t produces (e.g. []int from Int)
+// instead of a []interface{}.
+func ElemType(t ValueParser) ArgumentOption {
+	return func(a *Argument) error {
+		if err := Type(t)(a); err != nil {
+			return err
+		}
+		a.typedSlice = true
+		return nil
+	}
+}
+
+// Transform adds functions that change each of the argument's strings, in
+// order, before they're parsed by its Type (or looked up in its Choices).
+// See TrimSpace, ToLower, ExpandHome and ExpandEnv.
+func Transform(fs ...func(v string) string) ArgumentOption {
+	return func(a *Argument) error {
+		a.transforms = append(a.transforms, fs...)
+		return nil
+	}
+}
+
+// transform applies the argument's transforms to v.
+func (a *Argument) transform(v string) string {
+	for _, f := range a.transforms {
+		v = f(v)
+	}
+	return v
+}
+
+// Validate adds functions that check each of the argument's values after
+// they're parsed by its Type (or looked up in its Choices).  The first
+// error from the functions fails the parse.
+func Validate(fs ...func(v interface{}) error) ArgumentOption {
+	return func(a *Argument) error {
+		a.validators = append(a.validators, fs...)
+		return nil
+	}
+}
+
+// validate checks the value with the argument's validators.
+func (a *Argument) validate(v interface{}) error {
+	for _, f := range a.validators {
+		if err := f(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpandGlobs sets the argument's Type to Glob and stores the paths matched
+// by all of the argument's values as one list of values, so that patterns
+// like *.log work even when the shell (e.g. on Windows) doesn't expand them.
+func ExpandGlobs(a *Argument) error {
+	if err := Type(Glob)(a); err != nil {
+		return err
+	}
+	a.expandGlobs = true
+	return nil
+}
+
+// flattenGlobs replaces the []strings of paths from Glob with the paths.
+func flattenGlobs(vs []interface{}) []interface{} {
+	flat := make([]interface{}, 0, len(vs))
+	for _, v := range vs {
+		paths, ok := v.([]string)
+		if !ok {
+			flat = append(flat, v)
+			continue
+		}
+		for _, p := range paths {
+			flat = append(flat, p)
+		}
+	}
+	return flat
+}
+
 // MetaVar sets the help string of an argument.
 func MetaVar(v ...string) ArgumentOption {
 	return func(a *Argument) error {
@@ -473,6 +1151,13 @@ func OptionStrings(ops ...string) ArgumentOption {
 	}
 }
 
+// Clamp configures a range-constrained argument to snap out-of-range values
+// to the nearest bound instead of failing.
+func Clamp(a *Argument) error {
+	a.Clamp = true
+	return nil
+}
+
 // Required flags the Argument as required.
 func Required(a *Argument) error {
 	a.Required = true
@@ -496,24 +1181,110 @@ func (a *Argument) defaultCreateValues(args []interface{}) (vs []interface{}, er
 	vs = make([]interface{}, len(args))
 	if a.Choices != nil {
 		for i, arg := range args {
-			v, ok := a.Choices.Load(stringOf(arg))
+			v, ok := a.Choices.Load(a.transform(stringOf(arg)))
 			if !ok {
 				return nil, errors.Errorf(
 					"invalid choice %q for %v", v, a.Dest,
 				)
 			}
+			if err = a.validate(v); err != nil {
+				return nil, errors.ErrorfWithCause(
+					err, "invalid value %q of argument %q",
+					stringOf(arg), a.Dest)
+			}
 			vs[i] = v
 		}
 		return
 	}
 	for i, arg := range args {
-		if vs[i], err = a.Type(stringOf(arg)); err != nil {
-			return
+		if a.Nargs == Parser && i > 0 {
+			vs[i] = arg
+			continue
+		}
+		if vs[i], err = a.parseValue(a.transform(stringOf(arg))); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "invalid value %q of argument %q",
+				stringOf(arg), a.Dest)
 		}
+		if err = a.validate(vs[i]); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "invalid value %q of argument %q",
+				stringOf(arg), a.Dest)
+		}
+	}
+	if a.expandGlobs {
+		vs = flattenGlobs(vs)
 	}
 	return
 }
 
+// validateValue checks that v is a value that the argument could have
+// produced from parsing without parsing it again, because Types like
+// FileType have side effects.  Numbers are converted to the argument's
+// value type if that can be done without losing anything, e.g. the float64s
+// that encoding/json decodes for an Int64 argument.
+func (a *Argument) validateValue(v interface{}) (interface{}, error) {
+	if a.Choices != nil {
+		if _, ok := a.Choices.Load(stringOf(v)); !ok {
+			return nil, errors.Errorf(
+				"invalid choice %q for %v", stringOf(v), a.Dest)
+		}
+	} else if vt, ok := a.valueType(); ok {
+		cv, ok := convertNumber(v, vt)
+		if !ok {
+			return nil, errors.Errorf(
+				"value %[1]v (type: %[1]T) of %[2]v is not of "+
+					"type %[3]v", v, a.Dest, vt)
+		}
+		v = cv
+	}
+	if err := a.validate(v); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid value %v for %v", v, a.Dest)
+	}
+	return v, nil
+}
+
+// convertNumber gets v as a value of type t.  Numbers are converted
+// between numeric types only if converting them back produces the same
+// number.
+func convertNumber(v interface{}, t reflect.Type) (interface{}, bool) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil, false
+	}
+	if rv.Type().AssignableTo(t) {
+		return v, true
+	}
+	if !isNumberKind(rv.Kind()) || !isNumberKind(t.Kind()) {
+		return nil, false
+	}
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+			reflect.Int64:
+			if rv.Int() < 0 {
+				return nil, false
+			}
+		case reflect.Float32, reflect.Float64:
+			if rv.Float() < 0 {
+				return nil, false
+			}
+		}
+	}
+	cv := rv.Convert(t)
+	if cv.Convert(rv.Type()).Interface() != v {
+		return nil, false
+	}
+	return cv.Interface(), true
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return reflect.Int <= k && k <= reflect.Float64
+}
+
 func stringOf(v interface{}) string {
 	if s, ok := v.(string); ok {
 		return s
@@ -1,7 +1,11 @@
 package argparse
 
 import (
+	"encoding"
+	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/skillian/errors"
 )
@@ -17,6 +21,46 @@ type boundArg struct {
 type boundArgs []boundArg
 
 func (bs *boundArgs) bind(a *Argument, t interface{}) error {
+	if err := bs.ensureNotAlreadyBound(a); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(t)
+	if v.Kind() == reflect.Func {
+		return bs.bindFunc(a, v)
+	}
+	if v.Kind() != reflect.Ptr {
+		return errors.Errorf(
+			"target must be a pointer or a function, not %v "+
+				"(type: %T)",
+			v.Kind(), t,
+		)
+	}
+	return bs.bindValue(a, v.Elem())
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// bindFunc binds the argument to a function of one parameter that
+// optionally returns an error.  The function is called with the argument's
+// value converted to the parameter's type.
+func (bs *boundArgs) bindFunc(a *Argument, f reflect.Value) error {
+	ft := f.Type()
+	if f.IsNil() || ft.NumIn() != 1 || ft.IsVariadic() || ft.NumOut() > 1 ||
+		(ft.NumOut() == 1 && ft.Out(0) != errorType) {
+		return errors.Errorf(
+			"target function must be a func(T) or a "+
+				"func(T) error, not %v", ft)
+	}
+	if !a.useTextUnmarshaler(ft.In(0)) && a.parser.Strict {
+		if err := a.checkTarget(ft.In(0)); err != nil {
+			return err
+		}
+	}
+	*bs = append(*bs, boundArg{a, f})
+	return nil
+}
+
+func (bs *boundArgs) bindField(a *Argument, t interface{}, path string) error {
 	if err := bs.ensureNotAlreadyBound(a); err != nil {
 		return err
 	}
@@ -27,11 +71,111 @@ func (bs *boundArgs) bind(a *Argument, t interface{}) error {
 			v.Kind(), t,
 		)
 	}
-	v = v.Elem()
+	v, err := fieldByPath(v, path)
+	if err != nil {
+		return err
+	}
+	return bs.bindValue(a, v)
+}
+
+func (bs *boundArgs) bindValue(a *Argument, v reflect.Value) error {
+	if !a.useTextUnmarshaler(v.Type()) && a.parser.Strict {
+		if err := a.checkTarget(v.Type()); err != nil {
+			return err
+		}
+	}
 	*bs = append(*bs, boundArg{a, v})
 	return nil
 }
 
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// useTextUnmarshaler replaces the argument's default String Type with one
+// that calls UnmarshalText if values of the target type (or the element type
+// of a slice target) implement encoding.TextUnmarshaler.  It returns true
+// if the Type was replaced, in which case the values always fit the target.
+func (a *Argument) useTextUnmarshaler(tt reflect.Type) bool {
+	if a.Nargs == 0 || a.Choices != nil ||
+		reflect.ValueOf(a.Type).Pointer() != reflect.ValueOf(String).Pointer() {
+		return false
+	}
+	if tt.Kind() == reflect.Slice && !textUnmarshalable(tt) {
+		tt = tt.Elem()
+	}
+	if !textUnmarshalable(tt) {
+		return false
+	}
+	a.Type = func(v string) (interface{}, error) {
+		return unmarshalText(v, tt)
+	}
+	return true
+}
+
+// unmarshalText creates a value of type t (or what t points to) and
+// unmarshals v into it.
+func unmarshalText(v string, t reflect.Type) (interface{}, error) {
+	var pv reflect.Value
+	if t.Kind() == reflect.Ptr {
+		pv = reflect.New(t.Elem())
+	} else {
+		pv = reflect.New(t)
+	}
+	if err := pv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(v)); err != nil {
+		return nil, err
+	}
+	if t.Kind() == reflect.Ptr {
+		return pv.Interface(), nil
+	}
+	return pv.Elem().Interface(), nil
+}
+
+// textUnmarshalable returns true if values of type t (or what t points to)
+// can be unmarshaled with UnmarshalText.
+func textUnmarshalable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		return t.Implements(textUnmarshalerType)
+	}
+	return reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// fieldByPath gets the field from the struct v (or pointer to a struct)
+// with the given path of dot-separated field names.  Nil pointers to structs
+// along the path are allocated.
+func fieldByPath(v reflect.Value, path string) (reflect.Value, error) {
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, errors.Errorf(
+						"cannot allocate nil %v before "+
+							"field %q of %q",
+						v.Type(), name, path)
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, errors.Errorf(
+				"cannot get field %q of %q from %v",
+				name, path, v.Type())
+		}
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			return reflect.Value{}, errors.Errorf(
+				"%v has no field %q of %q",
+				v.Type(), name, path)
+		}
+		if !f.CanSet() {
+			return reflect.Value{}, errors.Errorf(
+				"field %q of %q in %v cannot be set",
+				name, path, v.Type())
+		}
+		v = f
+	}
+	return v, nil
+}
+
 func (bs *boundArgs) ensureNotAlreadyBound(a *Argument) error {
 	for _, b := range *bs {
 		if b.Argument == a {
@@ -47,22 +191,97 @@ func (bs *boundArgs) ensureNotAlreadyBound(a *Argument) error {
 	return nil
 }
 
+// BindError describes a bound argument whose value couldn't be assigned
+// to its target.
+type BindError struct {
+	// Dest is the Dest of the bound argument.
+	Dest string
+
+	// Value is the argument's value from the Namespace.
+	Value interface{}
+
+	// Target is the type of the bound target.
+	Target reflect.Type
+
+	// Err is the reason the value couldn't be assigned.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *BindError) Error() string {
+	return fmt.Sprintf(
+		"failed to bind %q value %v (type: %T) to target of "+
+			"type %v: %v",
+		e.Dest, e.Value, e.Value, e.Target, e.Err,
+	)
+}
+
+// BindErrors is returned from parsing when bound arguments' values couldn't
+// be assigned to their targets.  It has an error for every bound argument
+// that failed, not just the first one.
+type BindErrors []*BindError
+
+// Error implements the error interface.
+func (es BindErrors) Error() string {
+	strs := make([]string, len(es))
+	for i, e := range es {
+		strs[i] = e.Error()
+	}
+	return fmt.Sprintf(
+		"%d bound arguments failed:\n    %s",
+		len(es), strings.Join(strs, "\n    "))
+}
+
+// setValues assigns the bound arguments' values from the namespace to their
+// targets.  Targets of arguments that aren't in the namespace keep whatever
+// value they already had so that they can be initialized with defaults in
+// code before parsing.
 func (bs boundArgs) setValues(ns Namespace) error {
+	var errs BindErrors
 	for _, b := range bs {
 		i, ok := ns[b.Dest]
 		if !ok {
-			if err := reflectSetValue(b.Target, reflect.Zero(b.Target.Type())); err != nil {
-				return err
-			}
 			continue
 		}
-		if err := reflectSetValue(b.Target, reflect.ValueOf(i)); err != nil {
-			return err
+		if err := b.setValue(i); err != nil {
+			errs = append(errs, &BindError{
+				Dest:   b.Dest,
+				Value:  i,
+				Target: b.Target.Type(),
+				Err:    err,
+			})
 		}
 	}
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
+// setValue assigns the value to the bound target or calls the bound
+// function with it.
+func (b boundArg) setValue(i interface{}) error {
+	target := b.Target
+	if target.Kind() == reflect.Func {
+		target = reflect.New(target.Type().In(0)).Elem()
+	}
+	if i != nil {
+		if err := reflectSetValue(target, reflect.ValueOf(i)); err != nil {
+			return err
+		}
+	} else {
+		target.Set(reflect.Zero(target.Type()))
+	}
+	if b.Target.Kind() != reflect.Func {
+		return nil
+	}
+	out := b.Target.Call([]reflect.Value{target})
+	if len(out) == 0 || out[0].IsNil() {
+		return nil
+	}
+	return out[0].Interface().(error)
+}
+
 func reflectSetValue(target, value reflect.Value) error {
 	logger.Verbose(
 		"assigning to %v (type: %v) from %v (type: %v)",
@@ -86,20 +305,154 @@ func reflectSetValue(target, value reflect.Value) error {
 		tz := reflect.Zero(tt.Elem())
 		for i := 0; i < length; i++ {
 			ts = reflect.Append(ts, tz)
-			if err := reflectSetValue(
-				ts.Index(i),
-				value.Index(i).Elem(),
-			); err != nil {
-				return err
+			ev := value.Index(i)
+			if ev.Kind() == reflect.Interface {
+				if ev.IsNil() {
+					continue
+				}
+				ev = ev.Elem()
+			}
+			if err := reflectSetValue(ts.Index(i), ev); err != nil {
+				return errors.ErrorfWithCause(
+					err, "cannot assign element %d (type: %v) "+
+						"of %v to element of %v",
+					i, ev.Type(), vt, tt,
+				)
 			}
 		}
 		target.Set(ts)
+	case vt.Kind() == reflect.Map && tt.Kind() == reflect.Map:
+		tm := reflect.MakeMapWithSize(tt, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			tk := reflect.New(tt.Key()).Elem()
+			if err := reflectSetValue(tk, iter.Key()); err != nil {
+				return err
+			}
+			ev := iter.Value()
+			if ev.Kind() == reflect.Interface {
+				if ev.IsNil() {
+					tm.SetMapIndex(tk, reflect.Zero(tt.Elem()))
+					continue
+				}
+				ev = ev.Elem()
+			}
+			te := reflect.New(tt.Elem()).Elem()
+			if err := reflectSetValue(te, ev); err != nil {
+				return errors.ErrorfWithCause(
+					err, "cannot assign value of key %v "+
+						"(type: %v) of %v to element of %v",
+					iter.Key(), ev.Type(), vt, tt,
+				)
+			}
+			tm.SetMapIndex(tk, te)
+		}
+		target.Set(tm)
+	case tt.Kind() == reflect.Map && (vt.Kind() == reflect.Slice ||
+		vt.Kind() == reflect.String):
+		return reflectSetMap(target, value)
 	default:
 		return errors.Errorf(
 			"cannot assign value %[1]v (type: %[1]T) to "+
-				"target of type: %[2]v",
-			value.Interface(), target,
+				"target of type: %[2]v%[3]s",
+			value.Interface(), tt, parserHint(tt),
 		)
 	}
 	return nil
 }
+
+// reflectSetMap assigns "key=value" strings from value (a string or a slice
+// of them, e.g. from the Append action) to the map target.  The values are
+// parsed into the map's element type if they aren't strings.
+func reflectSetMap(target, value reflect.Value) error {
+	tt := target.Type()
+	if value.Kind() == reflect.String {
+		value = reflect.ValueOf([]string{value.String()})
+	}
+	tm := reflect.MakeMapWithSize(tt, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		ev := value.Index(i)
+		if ev.Kind() == reflect.Interface {
+			ev = ev.Elem()
+		}
+		if !ev.IsValid() || ev.Kind() != reflect.String {
+			return errors.Errorf(
+				"cannot assign element %d of %v to %v: "+
+					"expected a key=value string",
+				i, value.Type(), tt)
+		}
+		kv := ev.String()
+		j := strings.IndexByte(kv, '=')
+		if j < 1 {
+			return errors.Errorf(
+				"element %q of %v is not of the form key=value",
+				kv, value.Type())
+		}
+		tk := reflect.New(tt.Key()).Elem()
+		if err := reflectSetValue(tk, reflect.ValueOf(kv[:j])); err != nil {
+			return err
+		}
+		te := reflect.New(tt.Elem()).Elem()
+		v, err := parseElem(kv[j+1:], tt.Elem())
+		if err != nil {
+			return errors.ErrorfWithCause(
+				err, "cannot parse value of key %q as %v",
+				kv[:j], tt.Elem())
+		}
+		if err := reflectSetValue(te, reflect.ValueOf(v)); err != nil {
+			return err
+		}
+		tm.SetMapIndex(tk, te)
+	}
+	target.Set(tm)
+	return nil
+}
+
+// parseElem parses the string into a value of type t if t isn't a string
+// type.
+func parseElem(s string, t reflect.Type) (interface{}, error) {
+	switch {
+	case textUnmarshalable(t):
+		return unmarshalText(s, t)
+	case t == durationType:
+		return time.ParseDuration(s)
+	case t.Kind() == reflect.Bool:
+		return Bool(s)
+	}
+	if parse, ok := structFieldParsers[t.Kind()]; ok {
+		return parse(s)
+	}
+	return s, nil
+}
+
+// valueParserNames maps types to the names of the ValueParsers that
+// produce them so that conversion errors can suggest which Type option
+// would have worked.
+var valueParserNames = map[reflect.Type]string{
+	reflect.TypeOf(false):      "Bool",
+	reflect.TypeOf(float32(0)): "Float32",
+	reflect.TypeOf(float64(0)): "Float64",
+	reflect.TypeOf(int(0)):     "Int",
+	reflect.TypeOf(int8(0)):    "Int8",
+	reflect.TypeOf(int16(0)):   "Int16",
+	reflect.TypeOf(int32(0)):   "Int32",
+	reflect.TypeOf(int64(0)):   "Int64",
+	reflect.TypeOf(uint(0)):    "Uint",
+	reflect.TypeOf(uint8(0)):   "Uint8",
+	reflect.TypeOf(uint16(0)):  "Uint16",
+	reflect.TypeOf(uint32(0)):  "Uint32",
+	reflect.TypeOf(uint64(0)):  "Uint64",
+	reflect.TypeOf(""):         "String",
+}
+
+// parserHint suggests the ValueParser that produces values of type t, if
+// there is one.
+func parserHint(t reflect.Type) string {
+	name, ok := valueParserNames[t]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(
+		" (hint: use argparse.Type(argparse.%s) to parse values "+
+			"as %v)", name, t)
+}
@@ -0,0 +1,144 @@
+package argparse
+
+import (
+	"reflect"
+	"strings"
+)
+
+// redacted replaces the values of Secret arguments in the command line.
+const redacted = "REDACTED"
+
+// QuotedCommandLine renders the command line that produces the namespace's
+// values when parsed by p.  The values are quoted for POSIX shells and the
+// values of Secret arguments are redacted so that the command line can be
+// logged or pasted into a bug report.
+func (ns Namespace) QuotedCommandLine(p *ArgumentParser) string {
+	words := []string{shellQuote(p.Prog)}
+	for _, a := range p.getOptionals() {
+		v, ok := ns.Get(a)
+		if !ok || a.Action == ShowHelp || a.Action == ShowVersion {
+			continue
+		}
+		words = append(words, commandLineOption(a, v)...)
+	}
+	var poss []string
+	for _, a := range p.Positionals {
+		v, ok := ns.Get(a)
+		if !ok {
+			continue
+		}
+		poss = append(poss, commandLineValues(a, v)...)
+	}
+	for _, v := range poss {
+		if looksLikeOption(v) {
+			words = append(words, "--")
+			break
+		}
+	}
+	for _, v := range poss {
+		words = append(words, shellQuote(v))
+	}
+	return strings.Join(words, " ")
+}
+
+// commandLineOption gets the quoted words that set the optional argument a
+// to v.
+func commandLineOption(a *Argument, v interface{}) []string {
+	op := shellQuote(getLongestArgOptionString(a))
+	if a.Nargs == 0 {
+		switch {
+		case reflect.DeepEqual(v, a.Const):
+			return []string{op}
+		case a.Action == BooleanOptional && len(a.negations) > 0:
+			return []string{shellQuote(a.negations[0])}
+		case a.Action == AppendConst:
+			vs, _ := v.([]interface{})
+			words := make([]string, len(vs))
+			for i := range words {
+				words[i] = op
+			}
+			return words
+		}
+		return nil
+	}
+	vs := commandLineValues(a, v)
+	if a.Action == Append {
+		words := make([]string, 0, 2*len(vs))
+		for _, v := range vs {
+			words = append(words, op, shellQuote(v))
+		}
+		return words
+	}
+	words := make([]string, 1, len(vs)+1)
+	words[0] = op
+	for _, v := range vs {
+		words = append(words, shellQuote(v))
+	}
+	return words
+}
+
+// commandLineValues gets the unquoted args that produce the argument's
+// value(s).
+func commandLineValues(a *Argument, v interface{}) []string {
+	vs, ok := v.([]interface{})
+	if !ok {
+		vs = []interface{}{v}
+	}
+	ss := make([]string, 0, len(vs))
+	for _, v := range vs {
+		if v == nil {
+			continue
+		}
+		if a.Secret {
+			ss = append(ss, redacted)
+			continue
+		}
+		ss = append(ss, choiceKeyOf(a, v))
+	}
+	return ss
+}
+
+// choiceKeyOf gets the key of the argument's choice whose value is v or the
+// string form of v if the argument has no such choice.
+func choiceKeyOf(a *Argument, v interface{}) string {
+	if a.Choices != nil {
+		for i, limit := 0, a.Choices.Len(); i < limit; i++ {
+			if c := a.Choices.At(i); reflect.DeepEqual(c.Value, v) {
+				return c.Key
+			}
+		}
+	}
+	return stringOf(v)
+}
+
+// shellQuote quotes s for POSIX shells if it contains any characters that
+// the shell would interpret.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// getLongestArgOptionString gets the longest (i.e. most descriptive)
+// option string of the argument, skipping BooleanOptional negations.
+func getLongestArgOptionString(a *Argument) string {
+	long := ""
+	for _, s := range a.OptionStrings {
+		if !a.negation(s) && len(s) > len(long) {
+			long = s
+		}
+	}
+	return long
+}
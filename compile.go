@@ -0,0 +1,118 @@
+package argparse
+
+import (
+	"context"
+
+	"github.com/skillian/errors"
+)
+
+// compiledHelpWidths are the widths that a CompiledParser's help is
+// formatted at when it's compiled.
+var compiledHelpWidths = [...]int{80, 100, 120}
+
+// CompiledParser is a snapshot of an ArgumentParser that can be shared
+// between goroutines and parse command lines concurrently.  Adding
+// arguments to the ArgumentParser after it's compiled doesn't change the
+// CompiledParser, but the snapshot is shallow: the Arguments and the
+// sub-commands' parsers are shared with the ArgumentParser, so changing them
+// after compiling (e.g. with SetDefaults, RenameArgument or Subparsers'
+// AddParser) changes the CompiledParser too.  Finish configuring the parser
+// before compiling it.
+type CompiledParser struct {
+	// parser is a copy of the compiled parser with its own copies of the
+	// argument tables.
+	parser *ArgumentParser
+
+	// help holds the help formatted at each of compiledHelpWidths.
+	help [len(compiledHelpWidths)]string
+}
+
+// Compile creates a CompiledParser from the parser's current arguments.
+// Parsers with bound arguments (or with sub-commands with bound arguments)
+// cannot be compiled because concurrent parses would race to set the bound
+// targets.
+func (p *ArgumentParser) Compile() (*CompiledParser, error) {
+	if bp := p.boundParser(); bp != nil {
+		return nil, errors.Errorf(
+			"cannot compile parser %q because %q has bound "+
+				"arguments", p.Prog, bp.Prog)
+	}
+	cp := new(ArgumentParser)
+	*cp = *p
+	cp.Optionals = make(map[string]*Argument, len(p.Optionals))
+	for k, a := range p.Optionals {
+		cp.Optionals[k] = a
+	}
+	cp.Positionals = append([]*Argument(nil), p.Positionals...)
+	cp.args = append([]*Argument(nil), p.args...)
+	cp.groups = append([]*ArgumentGroup(nil), p.groups...)
+	cp.computeds = append([]computed(nil), p.computeds...)
+	cp.finalizers = append([]func(Namespace) error(nil), p.finalizers...)
+	c := &CompiledParser{parser: cp}
+	for i, width := range compiledHelpWidths {
+		s := helpingState{}
+		s.init(cp, width)
+		help, err := s.format()
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "failed to format help of %q", p.Prog)
+		}
+		c.help[i] = help
+	}
+	return c, nil
+}
+
+// boundParser gets the first parser with bound arguments of p and its
+// sub-commands' parsers or nil if none of them have bound arguments.
+func (p *ArgumentParser) boundParser() *ArgumentParser {
+	if len(p.boundArgs) > 0 {
+		return p
+	}
+	for _, sp := range p.Subparsers {
+		if bp := sp.boundParser(); bp != nil {
+			return bp
+		}
+	}
+	return nil
+}
+
+// MustCompile compiles the parser or panics if it cannot be compiled.
+func (p *ArgumentParser) MustCompile() *CompiledParser {
+	c, err := p.Compile()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// ParseArgs parses the args like the ArgumentParser's ParseArgs except that
+// it never defaults to os.Args[1:].
+func (c *CompiledParser) ParseArgs(args ...string) (Namespace, error) {
+	return c.ParseArgsContext(context.Background(), args...)
+}
+
+// ParseArgsContext parses the args like the ArgumentParser's
+// ParseArgsContext except that it never defaults to os.Args[1:].
+func (c *CompiledParser) ParseArgsContext(ctx context.Context, args ...string) (Namespace, error) {
+	s, err := c.parser.parseArgs(ctx, args, false)
+	if err != nil {
+		return nil, err
+	}
+	ns := s.ns
+	s.release()
+	return ns, nil
+}
+
+// FormatHelp gets the help formatted at the given width.  The help at the
+// common widths of 80, 100 and 120 columns is formatted when the parser is
+// compiled.
+func (c *CompiledParser) FormatHelp(width int) (string, error) {
+	for i, w := range compiledHelpWidths {
+		if w == width {
+			return c.help[i], nil
+		}
+	}
+	s := helpingState{}
+	s.init(c.parser, width)
+	return s.format()
+}
@@ -0,0 +1,138 @@
+package argparse
+
+import (
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+// CompletionItem is a candidate completion of a word on the command line.
+type CompletionItem struct {
+	// Value is the completed word.
+	Value string
+
+	// Help is the help text of the argument or choice that Value
+	// completes.
+	Help string
+}
+
+// Complete gets the completions of the word at index cursorWord in words
+// (which, like the args passed to ParseArgs, do not include the program
+// name).  cursorWord may be len(words) to complete a new, empty word.
+// Completions are produced for option strings and for the choices of the
+// argument whose value is being completed.
+func (p *ArgumentParser) Complete(words []string, cursorWord int) ([]CompletionItem, error) {
+	if cursorWord < 0 || cursorWord > len(words) {
+		return nil, errors.Errorf(
+			"cursor word index %d out of range [0, %d]",
+			cursorWord, len(words))
+	}
+	prefix := ""
+	if cursorWord < len(words) {
+		prefix = words[cursorWord]
+	}
+	s := parsingState{}
+	s.init(p, words[:cursorWord])
+	s.known = true
+	s.partial = true
+	if err := s.tokenize(); err != nil {
+		return nil, err
+	}
+	return s.complete(prefix), nil
+}
+
+// complete gets the completions of prefix after the args were tokenized.
+func (s *parsingState) complete(prefix string) (items []CompletionItem) {
+	a, required := s.pending()
+	if a != nil {
+		items = appendChoiceCompletions(items, a, prefix)
+	}
+	if required {
+		return
+	}
+	if !s.terminated && (prefix == "" || prefix[0] == '-') {
+		for _, a := range visibleArgs(s.parser.getOptionals()) {
+			for _, op := range a.OptionStrings {
+				if strings.HasPrefix(op, prefix) {
+					items = append(items, CompletionItem{
+						Value: op,
+						Help:  a.Help,
+					})
+				}
+			}
+		}
+	}
+	if s.posi < len(s.parser.Positionals) {
+		items = appendChoiceCompletions(
+			items, s.parser.Positionals[s.posi], prefix)
+	}
+	return
+}
+
+// pending gets the argument that the next arg can be a value of.  required
+// is true if the next arg must be a value of that argument (or, when the
+// argument is nil, that it cannot be completed at all).
+func (s *parsingState) pending() (a *Argument, required bool) {
+	if len(s.tokens) == 0 {
+		return nil, false
+	}
+	last := s.tokens[len(s.tokens)-1]
+	switch last.Kind {
+	case TokenOption, TokenValue, TokenPositional:
+		a = last.Argument
+	default:
+		return nil, false
+	}
+	count := 0
+	for i := len(s.tokens) - 1; i >= 0; i-- {
+		if s.tokens[i].Argument != a || s.tokens[i].Kind == TokenOption {
+			break
+		}
+		count++
+	}
+	switch a.Nargs {
+	case ZeroOrOne:
+		if count > 0 {
+			return nil, false
+		}
+		return a, false
+	case ZeroOrMore:
+		return a, false
+	case OneOrMore:
+		return a, count == 0
+	case Parser:
+		if count > 0 {
+			return nil, true
+		}
+		return a, true
+	default:
+		if count < a.Nargs {
+			return a, true
+		}
+		return nil, false
+	}
+}
+
+// appendChoiceCompletions appends the keys of a's choices (or suggested
+// values) that start with prefix to items.
+func appendChoiceCompletions(items []CompletionItem, a *Argument, prefix string) []CompletionItem {
+	if a.Choices != nil {
+		for i, limit := 0, a.Choices.Len(); i < limit; i++ {
+			items = appendChoiceCompletion(items, a.Choices.At(i), prefix)
+		}
+	}
+	for i := range a.suggestions {
+		items = appendChoiceCompletion(items, &a.suggestions[i], prefix)
+	}
+	return items
+}
+
+func appendChoiceCompletion(items []CompletionItem, c *Choice, prefix string) []CompletionItem {
+	if strings.HasPrefix(c.Key, prefix) {
+		items = append(items, CompletionItem{
+			Value: c.Key,
+			Help:  c.Help,
+		})
+	}
+	return items
+}
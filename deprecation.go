@@ -0,0 +1,126 @@
+package argparse
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+// deprecated returns true if the argument is deprecated.
+func (a *Argument) deprecated() bool {
+	return a.Deprecated != "" || a.RemovedIn != ""
+}
+
+// deprecationNote describes the argument's deprecation in its help and
+// warnings.
+func (a *Argument) deprecationNote() string {
+	note := "deprecated"
+	if a.RemovedIn != "" {
+		note += "; removed in " + a.RemovedIn
+	}
+	return note
+}
+
+// removed returns true if the argument's RemovedIn version is at or before
+// the parser's Version.
+func (a *Argument) removed() bool {
+	p := a.parser
+	return a.RemovedIn != "" && p != nil && p.Version != "" &&
+		compareVersions(a.RemovedIn, p.Version) <= 0
+}
+
+// checkDeprecated writes a warning when a deprecated argument is given as
+// op.  If the parser has StrictDeprecation, giving an argument that's
+// removed in the parser's version is an error.
+func (s *parsingState) checkDeprecated(a *Argument, op string) error {
+	if a.renamed(op) {
+		s.parser.warnf(
+			"%s is deprecated: use %s instead",
+			op, getLongestArgOptionString(a))
+		return nil
+	}
+	if !a.deprecated() {
+		return nil
+	}
+	if s.parser.StrictDeprecation && a.removed() {
+		return errors.Errorf(
+			"argument %q was removed in %s", op, a.RemovedIn)
+	}
+	msg := op + " is " + a.deprecationNote()
+	if a.Deprecated != "" {
+		msg += ": " + a.Deprecated
+	}
+	s.parser.warnf("%s", msg)
+	return nil
+}
+
+// compareVersions compares dotted version strings (optionally prefixed with
+// "v") numerically component by component.  Missing components are zero, so
+// "v2" == "2.0".  Components that aren't numbers are compared as strings.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for len(as) < len(bs) {
+		as = append(as, "0")
+	}
+	for len(bs) < len(as) {
+		bs = append(bs, "0")
+	}
+	for i := range as {
+		x, xerr := strconv.Atoi(as[i])
+		y, yerr := strconv.Atoi(bs[i])
+		if xerr != nil || yerr != nil {
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+			continue
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// RenameArgument keeps accepting oldOption after the argument it named was
+// renamed to the optional argument with the newDest Dest.  Values given
+// with oldOption are stored under newDest with a deprecation warning and
+// oldOption is left out of the help.
+func (p *ArgumentParser) RenameArgument(oldOption, newDest string) error {
+	if _, ok := p.Optionals[oldOption]; ok {
+		return errors.Errorf(
+			"cannot rename %q because it is still defined", oldOption)
+	}
+	for _, a := range p.args {
+		if a.Dest != newDest || !a.Optional() {
+			continue
+		}
+		a.renames = append(a.renames, oldOption)
+		p.Optionals[oldOption] = a
+		return nil
+	}
+	return errors.Errorf(
+		"cannot rename %q to %q: no optional argument has that Dest",
+		oldOption, newDest)
+}
+
+// MustRenameArgument panics if renaming the argument fails.
+func (p *ArgumentParser) MustRenameArgument(oldOption, newDest string) {
+	if err := p.RenameArgument(oldOption, newDest); err != nil {
+		panic(err)
+	}
+}
+
+// renamed returns true if op is one of the argument's old option strings.
+func (a *Argument) renamed(op string) bool {
+	for _, v := range a.renames {
+		if v == op {
+			return true
+		}
+	}
+	return false
+}
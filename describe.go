@@ -0,0 +1,88 @@
+package argparse
+
+import "encoding/json"
+
+// parserDescription is the JSON form of a parser written by DescribeJSON.
+type parserDescription struct {
+	Name        string                `json:"name,omitempty"`
+	Prog        string                `json:"prog"`
+	Description string                `json:"description,omitempty"`
+	Version     string                `json:"version,omitempty"`
+	Aliases     []string              `json:"aliases,omitempty"`
+	Arguments   []argumentDescription `json:"arguments"`
+	Commands    []parserDescription   `json:"commands,omitempty"`
+}
+
+// argumentDescription is the JSON form of an argument written by
+// DescribeJSON.
+type argumentDescription struct {
+	Dest          string      `json:"dest"`
+	OptionStrings []string    `json:"option_strings,omitempty"`
+	Nargs         interface{} `json:"nargs"`
+	MetaVar       []string    `json:"metavar,omitempty"`
+	Help          string      `json:"help,omitempty"`
+	Required      bool        `json:"required,omitempty"`
+	Choices       []string    `json:"choices,omitempty"`
+	EnvVar        string      `json:"env_var,omitempty"`
+	Persistent    bool        `json:"persistent,omitempty"`
+	Deprecated    string      `json:"deprecated,omitempty"`
+	RemovedIn     string      `json:"removed_in,omitempty"`
+}
+
+// DescribeJSON describes the parser's visible arguments and sub-commands
+// as JSON for tools like documentation generators.  Arguments' Nargs are
+// numbers or the "?", "*", "+" and "..." sentinels of Python's argparse.
+// Deprecated arguments include their Deprecated message and RemovedIn
+// version.
+func (p *ArgumentParser) DescribeJSON() ([]byte, error) {
+	return json.Marshal(p.describe())
+}
+
+func (p *ArgumentParser) describe() parserDescription {
+	d := parserDescription{
+		Name:        p.name,
+		Prog:        p.Prog,
+		Description: p.Description,
+		Version:     p.Version,
+		Aliases:     p.Aliases,
+		Arguments:   []argumentDescription{},
+	}
+	for _, a := range visibleArgs(p.args) {
+		d.Arguments = append(d.Arguments, a.describe())
+	}
+	for _, sp := range p.Subparsers {
+		d.Commands = append(d.Commands, sp.describe())
+	}
+	return d
+}
+
+func (a *Argument) describe() argumentDescription {
+	d := argumentDescription{
+		Dest:          a.Dest,
+		OptionStrings: a.OptionStrings,
+		Nargs:         a.Nargs,
+		MetaVar:       a.MetaVar,
+		Help:          a.Help,
+		Required:      a.Required,
+		EnvVar:        a.EnvVar,
+		Persistent:    a.Persistent,
+		Deprecated:    a.Deprecated,
+		RemovedIn:     a.RemovedIn,
+	}
+	switch a.Nargs {
+	case ZeroOrOne:
+		d.Nargs = "?"
+	case ZeroOrMore:
+		d.Nargs = "*"
+	case OneOrMore:
+		d.Nargs = "+"
+	case Parser:
+		d.Nargs = "..."
+	}
+	if a.Choices != nil {
+		for i, limit := 0, a.Choices.Len(); i < limit; i++ {
+			d.Choices = append(d.Choices, a.Choices.At(i).Key)
+		}
+	}
+	return d
+}
@@ -0,0 +1,139 @@
+package argparse
+
+import (
+	"reflect"
+
+	"github.com/skillian/errors"
+)
+
+// Parse creates a ValueParser from a function that parses strings into
+// values of type T.
+func Parse[T any](f func(string) (T, error)) ValueParser {
+	return func(v string) (interface{}, error) {
+		return f(v)
+	}
+}
+
+// Typed creates a function that parses strings into values of type T with
+// the given ValueParser, which must produce values of type T.
+func Typed[T any](parse ValueParser) func(string) (T, error) {
+	return func(v string) (t T, err error) {
+		x, err := parse(v)
+		if err != nil {
+			return t, err
+		}
+		t, ok := x.(T)
+		if !ok {
+			return t, errors.Errorf(
+				"expected %v from parsing %q, not %v "+
+					"(type: %[3]T)",
+				reflect.TypeOf(&t).Elem(), v, x)
+		}
+		return t, nil
+	}
+}
+
+// TypeFunc sets the argument's Type to parse values with f.  Unlike the Type
+// option, the type of the argument's values is known so that Strict parsers
+// can check the argument's bound targets.
+func TypeFunc[T any](f func(string) (T, error)) ArgumentOption {
+	return func(a *Argument) error {
+		if err := Type(Parse(f))(a); err != nil {
+			return err
+		}
+		a.typ = reflect.TypeOf((*T)(nil)).Elem()
+		return nil
+	}
+}
+
+// Get gets the argument's value from the namespace as a T.  Numbers are
+// converted to other numeric types as long as the conversion doesn't change
+// their values and slices (e.g. the []interface{} of multiple values) are
+// converted element by element.
+func Get[T any](ns Namespace, a *Argument) (t T, err error) {
+	v, ok := ns.Get(a)
+	if !ok {
+		return t, errors.Errorf(
+			"argument %q is not in the namespace", a.Dest)
+	}
+	if t, ok = v.(T); ok {
+		return t, nil
+	}
+	if v == nil {
+		return t, errors.Errorf(
+			"argument %q is nil, not %v", a.Dest,
+			reflect.TypeOf(&t).Elem())
+	}
+	tv := reflect.ValueOf(&t).Elem()
+	vv := reflect.ValueOf(v)
+	if err = getConvert(tv, vv); err != nil {
+		return t, errors.ErrorfWithCause(
+			err, "cannot get argument %q as %v", a.Dest, tv.Type())
+	}
+	return t, nil
+}
+
+// MustGet gets the argument's value from the namespace as a T or panics if
+// it cannot.
+func MustGet[T any](ns Namespace, a *Argument) T {
+	t, err := Get[T](ns, a)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// getConvert assigns the value to the target, converting numbers only if
+// their values are preserved.
+func getConvert(target, value reflect.Value) error {
+	tt, vt := target.Type(), value.Type()
+	switch {
+	case vt.AssignableTo(tt):
+		target.Set(value)
+		return nil
+	case isNumber(vt.Kind()) && isNumber(tt.Kind()):
+		cv := value.Convert(tt)
+		if cv.Convert(vt).Interface() != value.Interface() ||
+			isNegative(cv) != isNegative(value) {
+			return errors.Errorf(
+				"%v (type: %v) does not fit in %v",
+				value, vt, tt)
+		}
+		target.Set(cv)
+		return nil
+	case vt.Kind() == reflect.Slice && tt.Kind() == reflect.Slice:
+		ts := reflect.MakeSlice(tt, value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			ev := value.Index(i)
+			if ev.Kind() == reflect.Interface {
+				ev = ev.Elem()
+			}
+			if !ev.IsValid() {
+				continue
+			}
+			if err := getConvert(ts.Index(i), ev); err != nil {
+				return errors.ErrorfWithCause(
+					err, "cannot convert element %d", i)
+			}
+		}
+		target.Set(ts)
+		return nil
+	}
+	return errors.Errorf("%v is not convertible to %v", vt, tt)
+}
+
+// isNumber returns true for the kinds of integers and floats.
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+// isNegative returns true if the numeric value is less than zero.
+func isNegative(v reflect.Value) bool {
+	switch {
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		return v.Int() < 0
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return v.Float() < 0
+	}
+	return false
+}
@@ -1,6 +1,6 @@
 module github.com/skillian/argparse
 
-go 1.16
+go 1.18
 
 require (
 	github.com/skillian/errors v0.0.0-20190910214200-f19f31b303bd
@@ -0,0 +1,52 @@
+package argparse
+
+// ArgumentGroup is a collection of related arguments that are shown under
+// their own header in the help instead of under the "positional arguments"
+// and "optional arguments" headers.
+type ArgumentGroup struct {
+	// Title is the header of the group's arguments in the help.
+	Title string
+
+	// Description is shown under the Title in the help.
+	Description string
+
+	// parser is the parser the group's arguments are added to.
+	parser *ArgumentParser
+
+	// args are the arguments in the group in the order they were added.
+	args []*Argument
+}
+
+// AddArgumentGroup creates a group of related arguments that are shown
+// together under the title in the help.  Arguments added to the group are
+// parsed just like arguments added directly to the parser.
+func (p *ArgumentParser) AddArgumentGroup(title, description string) *ArgumentGroup {
+	g := &ArgumentGroup{
+		Title:       title,
+		Description: description,
+		parser:      p,
+	}
+	p.groups = append(p.groups, g)
+	return g
+}
+
+// AddArgument adds an argument to the group's parser and to the group.
+func (g *ArgumentGroup) AddArgument(options ...ArgumentOption) (*Argument, error) {
+	a, err := g.parser.AddArgument(options...)
+	if err != nil {
+		return nil, err
+	}
+	a.group = g
+	g.args = append(g.args, a)
+	return a, nil
+}
+
+// MustAddArgument adds an argument to the group or panics if argument
+// creation fails.
+func (g *ArgumentGroup) MustAddArgument(options ...ArgumentOption) *Argument {
+	a, err := g.AddArgument(options...)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
@@ -12,7 +12,10 @@ type helpingState struct {
 	// generated
 	parser *ArgumentParser
 
+	// opts and poss are the optional and positional arguments shown in
+	// the help.
 	opts []*Argument
+	poss []*Argument
 
 	// columns is the number of columns wide output should be.
 	columns int
@@ -33,10 +36,55 @@ type helpingState struct {
 
 func (s *helpingState) init(p *ArgumentParser, columns int) {
 	s.parser = p
-	s.opts = p.getOptionals(true)
+	s.opts = visibleArgs(p.getOptionals())
+	s.poss = visibleArgs(p.Positionals)
 	s.columns = columns
 	s.colspcs = strings.Repeat(" ", s.columns)
 	s.indent = 16
+	if p.AutoIndent {
+		s.indent = s.autoIndent()
+	}
+}
+
+const (
+	// minAutoIndent and maxAutoIndent bound the help's indent when it's
+	// computed from the arguments.
+	minAutoIndent = 8
+	maxAutoIndent = 32
+)
+
+// autoIndent computes the indent that fits the widest argument header.
+func (s *helpingState) autoIndent() int {
+	width := 0
+	var sb strings.Builder
+	for _, args := range [][]*Argument{s.poss, s.opts} {
+		for _, a := range args {
+			sb.Reset()
+			argHeader(a, &sb)
+			if sb.Len() > width {
+				width = sb.Len()
+			}
+		}
+	}
+	if ss := s.parser.subparsers; ss != nil {
+		for _, sp := range ss.parsers {
+			if n := len(commandLabel(sp)); n > width {
+				width = n
+			}
+		}
+	}
+	// 2 spaces before the header and at least 2 after it:
+	indent := width + 4
+	if indent < minAutoIndent {
+		indent = minAutoIndent
+	}
+	if indent > maxAutoIndent {
+		indent = maxAutoIndent
+	}
+	if indent > s.columns/2 {
+		indent = s.columns / 2
+	}
+	return indent
 }
 
 func (s *helpingState) format() (v string, err error) {
@@ -61,44 +109,20 @@ func (s *helpingState) format() (v string, err error) {
 	}
 	s.addArguments(
 		"positional arguments:",
-		s.parser.Positionals,
-		func(a *Argument, sb *strings.Builder) {
-			sb.WriteString(a.Dest)
-		})
+		ungroupedArgs(s.poss),
+		positionalHeader)
+	s.addCommands()
 	s.addArguments(
 		"optional arguments:",
-		s.opts,
-		func(a *Argument, sb *strings.Builder) {
-			for i, opt := range a.OptionStrings {
-				if i > 0 {
-					sb.WriteString(", ")
-				}
-				sb.WriteString(opt)
-				if len(a.MetaVar) > 0 {
-					sb.WriteByte(' ')
-					for j, mv := range a.MetaVar {
-						if j > 0 {
-							sb.WriteByte(' ')
-						}
-						sb.WriteString(mv)
-					}
-				}
-			}
-			if a.Choices != nil {
-				for j, limit := 0, a.Choices.Len(); j < limit; j++ {
-					ch := a.Choices.At(j)
-					if j == 0 {
-						sb.WriteString(" [ ")
-					} else {
-						sb.WriteString(" | ")
-					}
-					sb.WriteString(ch.Key)
-					if j == limit-1 {
-						sb.WriteString(" ]")
-					}
-				}
-			}
-		})
+		ungroupedArgs(s.opts),
+		optionalHeader)
+	for _, g := range s.parser.groups {
+		s.addGroup(g)
+	}
+	s.addArguments(
+		"global options:",
+		visibleArgs(s.parser.persistentArgs()),
+		optionalHeader)
 	if len(s.parser.Epilog) > 0 {
 		s.builder.WriteByte('\n')
 		s.builder.WriteString(
@@ -121,7 +145,7 @@ func (s *helpingState) addUsage() {
 	for _, a := range s.opts {
 		usages = append(usages, s.argUsage(a))
 	}
-	for _, a := range s.parser.Positionals {
+	for _, a := range s.poss {
 		usages = append(usages, s.argUsage(a))
 	}
 	s.writeStrings(
@@ -137,53 +161,205 @@ func (s *helpingState) addArguments(prefix string, args []*Argument, sel helpHea
 	}
 	s.writeStrings(prefix, "\n")
 	s.coli = 0
+	var head strings.Builder
 	for _, a := range args {
-		s.writeStrings("  ")
-		beforeHead := s.builder.Len()
-		sel(a, &s.builder)
-		s.coli = 2 + (s.builder.Len() - beforeHead)
-		if s.coli <= s.indent-2 {
-			s.writeStrings(s.colspcs[:s.indent-s.coli])
-		} else {
-			s.writeStrings("\n", s.colspcs[:s.indent])
-		}
-		s.coli = s.indent
-		for _, v := range strings.Split(textwrap.String(a.Help, s.columns-s.indent), "\n") {
-			s.writeStrings(s.colspcs[:s.indent-s.coli], v, "\n")
-			s.coli = 0
-		}
+		head.Reset()
+		sel(a, &head)
+		s.addEntry(head.String(), s.argHelp(a))
 		if a.Choices != nil {
 			s.writeSpaces(s.indent)
 			s.writeString("choices:\n")
-			choiceIndent := 2 * s.indent
 			for i, limit := 0, a.Choices.Len(); i < limit; i++ {
 				c := a.Choices.At(i)
-				s.writeSpaces(s.indent)
-				s.writeString(c.Key)
-				s.coli = s.indent + len(c.Key)
-				if s.coli < choiceIndent {
-					s.writeSpaces(choiceIndent - s.coli)
-				} else {
-					s.writeByte('\n')
-					s.writeSpaces(choiceIndent)
-				}
-				s.coli = choiceIndent
-				for _, v := range strings.Split(textwrap.String(
-					c.Help, s.columns-choiceIndent,
-				), "\n") {
-					s.writeSpaces(choiceIndent - s.coli)
-					s.writeString(v)
-					s.writeByte('\n')
-					s.coli = 0
-				}
+				s.addChoice(c.Key, c.Help)
 			}
 		}
 	}
 	s.writeStrings("\n")
 }
 
+// addEntry adds a line of the help with the head in the left column and the
+// help wrapped in the right column.
+func (s *helpingState) addEntry(head, help string) {
+	s.writeStrings("  ", head)
+	s.coli = 2 + len(head)
+	if s.coli <= s.indent-2 {
+		s.writeStrings(s.colspcs[:s.indent-s.coli])
+	} else {
+		s.writeStrings("\n", s.colspcs[:s.indent])
+	}
+	s.coli = s.indent
+	for _, v := range strings.Split(textwrap.String(help, s.columns-s.indent), "\n") {
+		s.writeStrings(s.colspcs[:s.indent-s.coli], v, "\n")
+		s.coli = 0
+	}
+}
+
+// addCommands adds the section listing the parser's sub-commands and their
+// short descriptions.
+func (s *helpingState) addCommands() {
+	ss := s.parser.subparsers
+	if ss == nil || ss.Hidden || ss.Help == Suppress || len(ss.parsers) == 0 {
+		return
+	}
+	s.writeStrings("commands:\n")
+	for _, sp := range ss.parsers {
+		help := sp.Description
+		if i := strings.IndexByte(help, '\n'); i != -1 {
+			help = help[:i]
+		}
+		if ss.Default != nil && stringOf(ss.Default) == sp.name {
+			help = strings.TrimSpace(help + " (default)")
+		}
+		s.addEntry(commandLabel(sp), help)
+	}
+	s.writeStrings("\n")
+}
+
+// addChoice adds a choice's key and help below an argument's help.
+func (s *helpingState) addChoice(key, help string) {
+	choiceIndent := 2 * s.indent
+	s.writeSpaces(s.indent)
+	s.writeString(key)
+	s.coli = s.indent + len(key)
+	if s.coli < choiceIndent {
+		s.writeSpaces(choiceIndent - s.coli)
+	} else {
+		s.writeByte('\n')
+		s.writeSpaces(choiceIndent)
+	}
+	s.coli = choiceIndent
+	for _, v := range strings.Split(textwrap.String(
+		help, s.columns-choiceIndent,
+	), "\n") {
+		s.writeSpaces(choiceIndent - s.coli)
+		s.writeString(v)
+		s.writeByte('\n')
+		s.coli = 0
+	}
+}
+
 type helpHeaderSelector func(a *Argument, sb *strings.Builder)
 
+// positionalHeader writes the header of a positional argument's help.
+func positionalHeader(a *Argument, sb *strings.Builder) {
+	if a.subparsers != nil && len(a.MetaVar) > 0 {
+		sb.WriteString(a.MetaVar[0])
+		return
+	}
+	sb.WriteString(a.Dest)
+}
+
+// optionalHeader writes the header of an optional argument's help.
+func optionalHeader(a *Argument, sb *strings.Builder) {
+	for i, opt := range a.OptionStrings {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(opt)
+		if len(a.MetaVar) > 0 {
+			sb.WriteByte(' ')
+			for j, mv := range a.MetaVar {
+				if j > 0 {
+					sb.WriteByte(' ')
+				}
+				sb.WriteString(mv)
+			}
+		}
+	}
+	if a.Choices != nil {
+		for j, limit := 0, a.Choices.Len(); j < limit; j++ {
+			ch := a.Choices.At(j)
+			if j == 0 {
+				sb.WriteString(" [ ")
+			} else {
+				sb.WriteString(" | ")
+			}
+			sb.WriteString(ch.Key)
+			if j == limit-1 {
+				sb.WriteString(" ]")
+			}
+		}
+	}
+}
+
+// argHeader writes the header of either a positional or optional argument.
+func argHeader(a *Argument, sb *strings.Builder) {
+	if a.Optional() {
+		optionalHeader(a, sb)
+	} else {
+		positionalHeader(a, sb)
+	}
+}
+
+// addGroup adds the help of an argument group's visible arguments under the
+// group's title and description.
+func (s *helpingState) addGroup(g *ArgumentGroup) {
+	args := visibleArgs(g.args)
+	if len(args) == 0 {
+		return
+	}
+	prefix := g.Title + ":"
+	if g.Description != "" {
+		for _, v := range strings.Split(textwrap.String(g.Description, s.columns-2), "\n") {
+			prefix += "\n  " + v
+		}
+		prefix += "\n"
+	}
+	s.addArguments(prefix, args, argHeader)
+}
+
+// ungroupedArgs gets the arguments that weren't added to an argument group.
+func ungroupedArgs(args []*Argument) []*Argument {
+	vs := make([]*Argument, 0, len(args))
+	for _, a := range args {
+		if a.group == nil {
+			vs = append(vs, a)
+		}
+	}
+	return vs
+}
+
+// visibleArgs gets the arguments that aren't hidden and whose help isn't
+// suppressed.
+func visibleArgs(args []*Argument) []*Argument {
+	vs := make([]*Argument, 0, len(args))
+	for _, a := range args {
+		if !a.Hidden && a.Help != Suppress {
+			vs = append(vs, a)
+		}
+	}
+	return vs
+}
+
+// argHelp gets the help text of the argument along with any annotations
+// derived from the argument's definition.
+func (s *helpingState) argHelp(a *Argument) string {
+	var notes []string
+	for _, c := range a.limits() {
+		notes = append(notes, "("+c+")")
+	}
+	for _, c := range a.constraints {
+		notes = append(notes, "("+c+")")
+	}
+	if a.EnvVar != "" {
+		notes = append(notes, "(env: "+a.EnvVar+")")
+	}
+	if a.deprecated() {
+		notes = append(notes, "("+a.deprecationNote()+")")
+	}
+	if s.parser.computed(a.Dest) {
+		notes = append(notes, "(default: computed)")
+	}
+	if len(notes) == 0 {
+		return a.Help
+	}
+	if a.Help == "" {
+		return strings.Join(notes, " ")
+	}
+	return a.Help + " " + strings.Join(notes, " ")
+}
+
 func (s *helpingState) argUsage(a *Argument) string {
 	var parts []string
 	if a.Optional() {
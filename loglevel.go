@@ -0,0 +1,51 @@
+//go:build go1.21
+// +build go1.21
+
+package argparse
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+// logLevels are the names of the slog levels accepted by LogLevel.
+var logLevels = []Choice{
+	{Key: "debug", Value: slog.LevelDebug},
+	{Key: "info", Value: slog.LevelInfo},
+	{Key: "warn", Value: slog.LevelWarn},
+	{Key: "error", Value: slog.LevelError},
+}
+
+// LogLevel converts the given string into a slog.Level value.  The level
+// can be one of the names debug, info, warn (or warning) and error in any
+// case, a name with an offset like "info+2" (see slog.Level's
+// UnmarshalText) or a number.
+// It implements the ValueParser interface.
+func LogLevel(v string) (interface{}, error) {
+	if i, err := strconv.Atoi(v); err == nil {
+		return slog.Level(i), nil
+	}
+	if strings.EqualFold(v, "warning") {
+		return slog.LevelWarn, nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(v)); err != nil {
+		return nil, errors.Errorf(
+			"invalid log level %q: expected debug, info, warn, "+
+				"error or a number", v)
+	}
+	return l, nil
+}
+
+// LogLevelType sets the Argument's Type to LogLevel and lists the level
+// names in the Argument's help and completions.
+func LogLevelType(a *Argument) error {
+	if err := Type(LogLevel)(a); err != nil {
+		return err
+	}
+	a.suggest(logLevels...)
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build go1.21
+// +build go1.21
+
+package argparse_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/skillian/argparse"
+)
+
+func TestLogLevel(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		v      string
+		expect slog.Level
+	}{
+		{"debug", slog.LevelDebug},
+		{"WARNING", slog.LevelWarn},
+		{"info+2", slog.LevelInfo + 2},
+		{"12", slog.Level(12)},
+	} {
+		v, err := argparse.LogLevel(tc.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.expect {
+			t.Fatalf("%q: expected %v but got %v", tc.v, tc.expect, v)
+		}
+	}
+	if _, err := argparse.LogLevel("loud"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	p := argparse.MustNewArgumentParser(argparse.Prog("test"))
+	p.MustAddArgument(
+		argparse.OptionStrings("--log-level"),
+		argparse.Action("store"),
+		argparse.LogLevelType)
+	items, err := p.Complete([]string{"--log-level", "d"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Value != "debug" {
+		t.Fatalf("unexpected completions: %v", items)
+	}
+}
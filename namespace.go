@@ -1,6 +1,14 @@
 package argparse
 
-import "github.com/skillian/errors"
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/skillian/errors"
+)
 
 // Namespace maps argument destination names with their values.  Values
 // are of the type the Argument's Type function converts them to (string, by
@@ -44,6 +52,9 @@ func (ns Namespace) MustGet(a *Argument) interface{} {
 // a slice of strings.
 func (ns Namespace) GetStrings(a *Argument) ([]string, error) {
 	v := ns.MustGet(a)
+	if ss, ok := v.([]string); ok {
+		return ss, nil
+	}
 	vs, ok := v.([]interface{})
 	if !ok {
 		return nil, errors.Errorf(
@@ -76,3 +87,202 @@ func (ns Namespace) MustGetStrings(a *Argument) []string {
 func (ns Namespace) Set(a *Argument, v interface{}) {
 	ns[a.Dest] = v
 }
+
+// Merge copies the values of other into ns.  Values of keys that are only
+// in other are always copied.  When both namespaces have a key:
+//
+//   - Maps (including nested Namespaces) are merged key by key with the
+//     same rules.
+//   - Other values, including slices, are replaced by other's values if
+//     overwrite is true and kept otherwise.
+//
+// Slices and maps of any type are copied so that changing ns afterwards
+// doesn't change other.
+func (ns Namespace) Merge(other Namespace, overwrite bool) {
+	mergeMaps(ns, other, overwrite)
+}
+
+func mergeMaps(dst, src map[string]interface{}, overwrite bool) {
+	for k, v := range src {
+		existing, ok := dst[k]
+		if !ok {
+			dst[k] = copyValue(v)
+			continue
+		}
+		if dm, ok := asMap(existing); ok {
+			if sm, ok := asMap(v); ok {
+				mergeMaps(dm, sm, overwrite)
+				continue
+			}
+		}
+		if overwrite {
+			dst[k] = copyValue(v)
+		}
+	}
+}
+
+// asMap gets v as a map if it's a Namespace or map[string]interface{}.
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case Namespace:
+		return m, m != nil
+	case map[string]interface{}:
+		return m, m != nil
+	}
+	return nil, false
+}
+
+// copyValue copies slices and maps (e.g. []interface{}, the []int of an
+// ElemType argument or the map[string]string of StoreMap) so that the copy
+// can be changed independently of v.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		vs := make([]interface{}, len(v))
+		for i, e := range v {
+			vs[i] = copyValue(e)
+		}
+		return vs
+	case Namespace:
+		m := make(Namespace, len(v))
+		mergeMaps(m, v, true)
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		mergeMaps(m, v, true)
+		return m
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			return v
+		}
+		cv := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			cv.Index(i).Set(copyElem(rv.Index(i)))
+		}
+		return cv.Interface()
+	case reflect.Map:
+		if rv.IsNil() {
+			return v
+		}
+		cv := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		for it := rv.MapRange(); it.Next(); {
+			cv.SetMapIndex(it.Key(), copyElem(it.Value()))
+		}
+		return cv.Interface()
+	}
+	return v
+}
+
+// copyElem copies an element of a slice or map with copyValue.
+func copyElem(e reflect.Value) reflect.Value {
+	switch e.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Interface:
+		if e.IsNil() {
+			return e
+		}
+		return reflect.ValueOf(copyValue(e.Interface())).Convert(e.Type())
+	}
+	return e
+}
+
+// Keys gets the namespace's keys.  Given the parsers that parsed the
+// namespace (e.g. ns.Keys(p) or, for a sub-command's arguments too,
+// ns.Keys(p, build)), the keys of their arguments' Dests come first in the
+// order that the arguments were defined.  The other keys (e.g. computed
+// values, or every key if no parsers are given) follow in sorted order.
+func (ns Namespace) Keys(parsers ...*ArgumentParser) []string {
+	keys := make([]string, 0, len(ns))
+	seen := make(map[string]bool, len(ns))
+	for _, p := range parsers {
+		for _, a := range p.args {
+			if _, ok := ns[a.Dest]; ok && !seen[a.Dest] {
+				keys = append(keys, a.Dest)
+				seen[a.Dest] = true
+			}
+		}
+	}
+	defined := len(keys)
+	for k := range ns {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys[defined:])
+	return keys
+}
+
+// Range calls f with the namespace's keys and values in the order of Keys
+// with the same parsers until f returns false.
+func (ns Namespace) Range(f func(k string, v interface{}) bool, parsers ...*ArgumentParser) {
+	for _, k := range ns.Keys(parsers...) {
+		if !f(k, ns[k]) {
+			return
+		}
+	}
+}
+
+// Sub gets the namespace of the named sub-command when the parser has
+// NestedNamespaces.  It is nil if the sub-command wasn't selected.
+func (ns Namespace) Sub(name string) Namespace {
+	switch sub := ns[name].(type) {
+	case Namespace:
+		return sub
+	case map[string]interface{}:
+		// e.g. after a round-trip through JSON
+		return Namespace(sub)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.  The keys are sorted so that the
+// output is stable.
+func (ns Namespace) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}(ns))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  Numbers without a fraction or
+// exponent that fit in an int are decoded as ints and other numbers are
+// float64s so that a marshaled Namespace's int values round-trip.  Arrays
+// are decoded as []interface{} like the values of arguments with multiple
+// values.
+func (ns *Namespace) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+	if m == nil {
+		*ns = nil
+		return nil
+	}
+	for k, v := range m {
+		m[k] = jsonNumbers(v)
+	}
+	*ns = Namespace(m)
+	return nil
+}
+
+// jsonNumbers replaces the json.Numbers in v with ints or float64s.
+func jsonNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, strconv.IntSize); err == nil {
+			return int(i)
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonNumbers(e)
+		}
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = jsonNumbers(e)
+		}
+	}
+	return v
+}
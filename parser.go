@@ -1,12 +1,14 @@
 package argparse
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
-	"sort"
 	"strings"
+	"time"
 
 	"github.com/skillian/errors"
 )
@@ -37,25 +39,153 @@ type ArgumentParser struct {
 	Epilog string
 
 	// Subparsers holds a slice of sub-parsers when your top-level parser
-	// has different sub-commands.
+	// has different sub-commands.  It is populated by the AddParser
+	// method of the Subparsers returned from AddSubparsers.
 	Subparsers []*ArgumentParser
 
+	// Aliases are alternative names of a sub-command's parser that
+	// select it just like its name does.
+	Aliases []string
+
 	// Parents includes a collection of ArgumentParser objects whose
-	// arguments should be included in this ArgumentParser.  We're keeping
-	// it simple for now, though.
-	//Parents []*ArgumentParser
+	// arguments should be included in this ArgumentParser.  The parents'
+	// arguments (except for their help arguments) are copied into this
+	// parser when it's created.
+	Parents []*ArgumentParser
 
 	//FormatterClass reflect.Type
 	//PrefixChars []rune
 	//FromFilePrefixChars []rune
 	//ArgumentDefault *Argument
-	//ConflictHandler interface{}
+
+	// ConflictHandler determines what happens when an argument is added
+	// with an option string that's already defined.  With
+	// ConflictError (the default), AddArgument returns an error.  With
+	// ConflictResolve, the new argument takes the option string from the
+	// existing argument, and the existing argument is removed if it has no
+	// option strings left.
+	ConflictHandler string
 
 	// NoHelp is false when the ArgumentParser should add the -h/--help
 	// arguments to generate help output.  It is analogous to the add_help
 	// attribute on the ArgumentParser class in Python.
 	NoHelp bool
 
+	// HelpOptionStrings are the option strings of the help argument.  They
+	// default to -h and --help.
+	HelpOptionStrings []string
+
+	// HelpStdout makes the help argument write the help to Stdout and exit
+	// with status 0 (like Python's argparse) instead of writing it to
+	// Stderr and exiting with status 1, so that the help can be piped.
+	HelpStdout bool
+
+	// HelpTheme colors the help written by the help argument.  It is
+	// ThemeAuto, ThemeLight, ThemeDark or empty to not color the help.
+	// ThemeAuto only colors help written to a terminal (unless NO_COLOR
+	// is set) and picks the palette from the terminal's background.
+	HelpTheme string
+
+	// AutoIndent computes the width of the help's left column (the
+	// arguments' option strings and metavars) from the widest argument
+	// instead of using a fixed width.  The width is capped so that one
+	// long option doesn't squeeze every argument's help text.
+	AutoIndent bool
+
+	// GNUErrors makes parsing errors use the exact phrasing of GNU
+	// getopt_long (e.g. "prog: unrecognized option '--foo'") so that
+	// callers that inspect the error output of a tool being replaced
+	// keep working.
+	GNUErrors bool
+
+	// NestedNamespaces stores the values of the parser's sub-commands in
+	// their own Namespaces under the sub-commands' names (see Namespace's
+	// Sub method) instead of merging them into the parser's Namespace so
+	// that sub-commands' arguments with the same Dest don't collide.
+	NestedNamespaces bool
+
+	// Strict requires every argument that takes values to declare its
+	// Type explicitly and verifies that every bound target can hold the
+	// argument's values when it is bound so that mistakes in the argument
+	// definitions fail at startup instead of when the arguments are
+	// parsed.
+	Strict bool
+
+	// StrictDeprecation makes giving an argument whose RemovedIn version
+	// is at or before the parser's Version an error instead of a warning.
+	StrictDeprecation bool
+
+	// Version is the program's version printed by the version action.
+	Version string
+
+	// Stdin is where answers to prompts are read from.  If it is nil,
+	// os.Stdin is used.
+	Stdin io.Reader
+
+	// lines reads the answers to prompts from Stdin when it's set.
+	lines *lineReader
+
+	// Stdout is where the version (and help, if HelpStdout is set) is
+	// written.  If it is nil, os.Stdout is used.
+	Stdout io.Writer
+
+	// Stderr is where warnings and prompts are written.  If it is nil,
+	// os.Stderr is used.
+	Stderr io.Writer
+
+	// Run is the handler of the parser's command that Execute calls
+	// with the parsed namespace.
+	Run RunFunc
+
+	// Stats, if set, is called with the statistics of every parse so
+	// that authors of large command lines can find slow custom
+	// ValueParsers.  Statistics aren't collected if it's nil.
+	Stats func(stats ParseStats)
+
+	// Exit is called with the exit status when parsing ends the program
+	// (e.g. after the version is printed).  If it is nil, os.Exit is
+	// used.
+	Exit func(code int)
+
+	// test holds what's captured from a parser in TestMode.  It is nil
+	// when the parser isn't in TestMode.
+	test *testCapture
+
+	// defaults are the default values set with SetDefaults whose keys
+	// aren't any argument's Dest.
+	defaults map[string]interface{}
+
+	// middleware wrap the Run handlers of the parser's command and its
+	// sub-commands.
+	middleware []Middleware
+
+	// finalizers are called with the parsed Namespace after defaults are
+	// applied but before any bound targets are set.
+	finalizers []func(ns Namespace) error
+
+	// computeds holds the values computed from the Namespace after
+	// parsing if they were not otherwise provided.
+	computeds []computed
+
+	// args holds every argument in the order they were added.  Anything
+	// that iterates over the arguments should use args (directly or via
+	// getOptionals) so that its output is deterministic.
+	args []*Argument
+
+	// name is the sub-command name the parser was added with by
+	// Subparsers' AddParser.
+	name string
+
+	// parent is the parser that the parser is a sub-command of.  It is
+	// nil for the top-level parser.
+	parent *ArgumentParser
+
+	// subparsers are the parser's sub-commands, if any.
+	subparsers *Subparsers
+
+	// groups are the argument groups in the order they were added.
+	groups []*ArgumentGroup
+
 	// boundArgs is a collection of arguments and their bound targets
 	// which are set after parsing arguments.
 	boundArgs
@@ -78,9 +208,54 @@ func NewArgumentParser(options ...ArgumentParserOption) (*ArgumentParser, error)
 	if p.Prog == "" {
 		p.Prog = filepath.Base(os.Args[0])
 	}
+	if !p.NoHelp {
+		if len(p.HelpOptionStrings) == 0 {
+			p.HelpOptionStrings = []string{"-h", "--help"}
+		}
+		if _, err := p.AddArgument(
+			ActionFunc(ShowHelp),
+			OptionStrings(p.HelpOptionStrings...),
+			Help("show this help message and exit"),
+		); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "failed to add help argument")
+		}
+	}
+	for _, parent := range p.Parents {
+		if err := p.inherit(parent); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "failed to add arguments from parent "+
+					"parser %q", parent.Prog)
+		}
+	}
 	return p, nil
 }
 
+// inherit copies the parent's arguments and argument groups into p.
+func (p *ArgumentParser) inherit(parent *ArgumentParser) error {
+	groups := make(map[*ArgumentGroup]*ArgumentGroup, len(parent.groups))
+	for _, g := range parent.groups {
+		groups[g] = p.AddArgumentGroup(g.Title, g.Description)
+	}
+	for _, pa := range parent.args {
+		if pa.Action == ShowHelp {
+			continue
+		}
+		a := new(Argument)
+		*a = *pa
+		a.parser = p
+		a.group = nil
+		if err := p.addArgument(a); err != nil {
+			return err
+		}
+		if g, ok := groups[pa.group]; ok {
+			a.group = g
+			g.args = append(g.args, a)
+		}
+	}
+	return nil
+}
+
 // MustNewArgumentParser creates an argument parser and panics if creation fails.
 func MustNewArgumentParser(options ...ArgumentParserOption) *ArgumentParser {
 	p, err := NewArgumentParser(options...)
@@ -103,6 +278,13 @@ func (p *ArgumentParser) AddArgument(options ...ArgumentOption) (*Argument, erro
 		a.Action = Store
 	}
 	if a.Type == nil {
+		if p.Strict && a.Nargs != 0 {
+			return nil, errors.Errorf(
+				"argument with option strings %v and dest "+
+					"%q must have an explicit Type in a "+
+					"strict parser",
+				a.OptionStrings, a.Dest)
+		}
 		a.Type = String
 	}
 	if a.Dest == "" {
@@ -116,9 +298,21 @@ func (p *ArgumentParser) AddArgument(options ...ArgumentOption) (*Argument, erro
 		}
 		a.Dest = dest
 	}
+	if a.Action == BooleanOptional && len(a.negations) == 0 {
+		for _, op := range a.OptionStrings {
+			if strings.HasPrefix(op, "--") {
+				a.negations = append(a.negations, "--no-"+op[2:])
+			}
+		}
+		ops := make([]string, 0, len(a.OptionStrings)+len(a.negations))
+		ops = append(ops, a.OptionStrings...)
+		a.OptionStrings = append(ops, a.negations...)
+	}
 	if len(a.MetaVar) == 0 && a.Nargs != 0 && a.Choices == nil {
 		upper := strings.ToUpper(a.Dest)
-		if a.Nargs < 0 || a.Nargs == 1 {
+		if a.Nargs == Parser {
+			a.MetaVar = []string{upper, "..."}
+		} else if a.Nargs < 0 || a.Nargs == 1 {
 			a.MetaVar = []string{upper}
 		} else {
 			a.MetaVar = make([]string, a.Nargs)
@@ -128,12 +322,22 @@ func (p *ArgumentParser) AddArgument(options ...ArgumentOption) (*Argument, erro
 		}
 
 	}
-	// add to parser:
+	if err := p.addArgument(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// addArgument adds an argument whose definition is complete to the parser.
+func (p *ArgumentParser) addArgument(a *Argument) error {
 	if a.Optional() {
 		for _, op := range a.OptionStrings {
 			if _, ok := p.Optionals[op]; ok {
-				return nil, errors.Errorf(
-					"redefinition of option: %q", op)
+				if p.ConflictHandler != ConflictResolve {
+					return errors.Errorf(
+						"redefinition of option: %q", op)
+				}
+				p.resolveConflict(op)
 			}
 		}
 		for _, op := range a.OptionStrings {
@@ -142,8 +346,39 @@ func (p *ArgumentParser) AddArgument(options ...ArgumentOption) (*Argument, erro
 	} else {
 		p.Positionals = append(p.Positionals, a)
 	}
+	p.args = append(p.args, a)
+	return nil
+}
 
-	return a, nil
+// resolveConflict removes the option string from the argument it's already
+// defined for and removes that argument if it has no option strings left.
+func (p *ArgumentParser) resolveConflict(op string) {
+	a := p.Optionals[op]
+	delete(p.Optionals, op)
+	ops := make([]string, 0, len(a.OptionStrings))
+	for _, v := range a.OptionStrings {
+		if v != op {
+			ops = append(ops, v)
+		}
+	}
+	a.OptionStrings = ops
+	if len(ops) > 0 {
+		return
+	}
+	p.args = removeArgument(p.args, a)
+	if a.group != nil {
+		a.group.args = removeArgument(a.group.args, a)
+	}
+}
+
+// removeArgument removes a from args.
+func removeArgument(args []*Argument, a *Argument) []*Argument {
+	for i, v := range args {
+		if v == a {
+			return append(args[:i:i], args[i+1:]...)
+		}
+	}
+	return args
 }
 
 // MustAddArgument adds an argument or panics if argument creation fails.
@@ -159,20 +394,208 @@ func (p *ArgumentParser) MustAddArgument(options ...ArgumentOption) *Argument {
 // a namespace from those args.  If any arguments were bound from an Argument,
 // those targets are assigned to.
 func (p *ArgumentParser) ParseArgs(args ...string) (Namespace, error) {
-	s := parsingState{}
-	if len(args) == 0 {
-		args = os.Args[1:]
+	return p.ParseArgsContext(context.Background(), args...)
+}
+
+// ParseArgsContext works like ParseArgs but any prompts for argument values
+// are cancelled when the context is done, in which case ErrPromptCancelled
+// is returned.
+func (p *ArgumentParser) ParseArgsContext(ctx context.Context, args ...string) (Namespace, error) {
+	args = p.defaultArgs(args)
+	s, err := p.parseArgs(ctx, args, false)
+	if err != nil {
+		return nil, err
+	}
+	ns := s.ns
+	s.release()
+	return ns, nil
+}
+
+// ParseKnownArgs works like ParseArgs except that unrecognized options and
+// extra positional arguments do not produce an error.  Instead, they are
+// returned in the order they were encountered so that they can be forwarded
+// elsewhere (e.g. to a child process).
+func (p *ArgumentParser) ParseKnownArgs(args ...string) (Namespace, []string, error) {
+	args = p.defaultArgs(args)
+	s, err := p.parseArgs(context.Background(), args, true)
+	if err != nil {
+		return nil, nil, err
 	}
-	p.handleHelp(args)
+	ns, extras := s.ns, s.extras
+	s.release()
+	return ns, extras, nil
+}
+
+// SetArgs holds the arguments that were given on the command line, as
+// opposed to having their default values (or not being in the namespace).
+type SetArgs map[*Argument]bool
+
+// WasSet returns true if the argument was given on the command line.
+func (set SetArgs) WasSet(a *Argument) bool {
+	return set[a]
+}
+
+// ParseArgsSet works like ParseArgs but also returns the arguments that
+// were given on the command line (including those given to a sub-command)
+// so that they can be told apart from arguments with their default values
+// (e.g. so that a value from a config file only replaces defaults).
+func (p *ArgumentParser) ParseArgsSet(args ...string) (Namespace, SetArgs, error) {
+	args = p.defaultArgs(args)
+	s, err := p.parseArgs(context.Background(), args, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	ns, set := s.ns, s.set
+	s.release()
+	return ns, set, nil
+}
+
+// Tokenize classifies the given args into tokens without evaluating them.
+// The tokens can be inspected or modified before they are passed to
+// Evaluate.  Unlike ParseArgs, Tokenize does not default to os.Args[1:].
+func (p *ArgumentParser) Tokenize(args ...string) ([]Token, error) {
+	s := parsingState{}
 	s.init(p, args)
-	var err error
-	if err = s.parse(); err != nil {
+	if err := s.tokenize(); err != nil {
+		return nil, err
+	}
+	return s.tokens, nil
+}
+
+// Evaluate creates a namespace from tokens produced by Tokenize.  If any
+// arguments were bound from an Argument, those targets are assigned to.
+func (p *ArgumentParser) Evaluate(tokens []Token) (Namespace, error) {
+	return p.evaluateTokens(tokens, false)
+}
+
+// evaluateTokens creates a namespace from tokens.  If remote is true, the
+// tokens didn't come from a terminal (see ParseValues).
+func (p *ArgumentParser) evaluateTokens(tokens []Token, remote bool) (Namespace, error) {
+	s := getParsingState(p, nil)
+	s.tokens = append(s.tokens, tokens...)
+	s.remote = remote
+	if err := p.evaluate(s); err != nil {
+		s.release()
 		return nil, err
 	}
-	if err = p.boundArgs.setValues(s.ns); err != nil {
+	ns := s.ns
+	s.release()
+	return ns, nil
+}
+
+// parseArgs tokenizes and evaluates the args.  The returned state must be
+// released after its results are used.
+func (p *ArgumentParser) parseArgs(ctx context.Context, args []string, known bool) (*parsingState, error) {
+	s := getParsingState(p, args)
+	s.ctx = ctx
+	s.known = known
+	if err := p.parse(s); err != nil {
+		s.release()
 		return nil, err
 	}
-	return s.ns, nil
+	return s, nil
+}
+
+// parse tokenizes and evaluates the state's args.
+func (p *ArgumentParser) parse(s *parsingState) error {
+	if err := s.tokenize(); err != nil {
+		return err
+	}
+	return p.evaluate(s)
+}
+
+// evaluate builds the state's namespace from its tokens.
+func (p *ArgumentParser) evaluate(s *parsingState) (err error) {
+	if p.Stats != nil {
+		s.stats = &ParseStats{Tokens: len(s.tokens)}
+		start := time.Now()
+		defer func() {
+			s.stats.Duration = time.Since(start)
+			p.Stats(*s.stats)
+		}()
+	}
+	if err = s.evaluate(); err != nil {
+		return err
+	}
+	if err = s.typeSlices(); err != nil {
+		return err
+	}
+	for k, v := range p.defaults {
+		if _, ok := s.ns[k]; !ok {
+			s.ns[k] = v
+		}
+	}
+	for _, c := range p.computeds {
+		if _, ok := s.ns[c.dest]; ok {
+			continue
+		}
+		v, err := c.f(s.ns)
+		if err != nil {
+			return errors.ErrorfWithCause(
+				err, "failed to compute %q", c.dest,
+			)
+		}
+		s.ns[c.dest] = v
+	}
+	for _, f := range p.finalizers {
+		if err = f(s.ns); err != nil {
+			return err
+		}
+	}
+	if s.remote {
+		return nil
+	}
+	return p.boundArgs.setValues(s.ns)
+}
+
+// Execute parses the given args (or os.Args[1:], if none specified) and
+// calls the Run handler of the deepest sub-command selected by the args (or
+// of p itself if no sub-command is selected) with the namespace.  The
+// handler's error is returned.
+func (p *ArgumentParser) Execute(args ...string) error {
+	return p.ExecuteContext(context.Background(), args...)
+}
+
+// ExecuteContext works like Execute but prompts for argument values are
+// cancelled when the context is done.
+func (p *ArgumentParser) ExecuteContext(ctx context.Context, args ...string) error {
+	args = p.defaultArgs(args)
+	s, err := p.parseArgs(ctx, args, false)
+	if err != nil {
+		return err
+	}
+	cmd, ns := s.command, s.ns
+	s.release()
+	if cmd.Run == nil {
+		return errors.Errorf(
+			"no Run handler for command %q", cmd.Prog)
+	}
+	run := cmd.Run
+	for q := cmd; q != nil; q = q.parent {
+		for i := len(q.middleware) - 1; i >= 0; i-- {
+			run = q.middleware[i](run)
+		}
+		if q == p {
+			break
+		}
+	}
+	return run(ns)
+}
+
+// RunFunc is the handler of a command that's called by Execute with the
+// parsed namespace.
+type RunFunc func(ns Namespace) error
+
+// Middleware wraps a command's RunFunc, e.g. to set up logging or load
+// configuration before calling next.
+type Middleware func(next RunFunc) RunFunc
+
+// Use adds middleware that wraps the Run handlers of the parser's command
+// and all of its sub-commands when they're called by Execute.  Middleware
+// is called in the order it's added, with a parser's middleware wrapping
+// that of its sub-commands.
+func (p *ArgumentParser) Use(mw ...Middleware) {
+	p.middleware = append(p.middleware, mw...)
 }
 
 // MustParseArgs must parse its arguments or it will panic.
@@ -184,43 +607,194 @@ func (p *ArgumentParser) MustParseArgs(args ...string) Namespace {
 	return ns
 }
 
-func (p *ArgumentParser) getOptionals(sorted bool) []*Argument {
-	// might as well allocate enough...
-	args := make([]*Argument, 0, len(p.Optionals))
-	already := make(map[*Argument]struct{})
-	for _, a := range p.Optionals {
-		if _, ok := already[a]; ok {
+// ValidateNamespace checks a Namespace that wasn't produced by parsing (e.g.
+// one decoded from JSON) against the parser's arguments:  Required
+// arguments must be present and values must be valid choices or be of the
+// type that the argument's Type produces and pass the argument's
+// validators.  Numbers that can be converted to that type without loss are
+// replaced in ns, so an Int64 argument decoded from JSON as a float64 is
+// an int64 afterwards.
+func (p *ArgumentParser) ValidateNamespace(ns Namespace) error {
+	for _, a := range p.args {
+		v, ok := ns.Get(a)
+		if !ok {
+			if a.Required {
+				return errors.Errorf(
+					"missing required argument %q", a.Dest)
+			}
 			continue
 		}
-		args = append(args, a)
-		already[a] = struct{}{}
+		if a.Nargs == 0 {
+			// the value is the argument's Const or Default.
+			continue
+		}
+		vs, ok := v.([]interface{})
+		if !ok {
+			cv, err := a.validateValue(v)
+			if err != nil {
+				return err
+			}
+			ns.Set(a, cv)
+			continue
+		}
+		if a.Nargs == Parser && len(vs) > 0 {
+			vs = vs[:1]
+		}
+		for i, v := range vs {
+			cv, err := a.validateValue(v)
+			if err != nil {
+				return err
+			}
+			vs[i] = cv
+		}
+	}
+	return nil
+}
+
+// SetDefaults sets the Default of the parser's arguments with the defaults'
+// keys as their Dests.  Keys that aren't any argument's Dest are added to the
+// parsed Namespace if they're not otherwise set, e.g. to associate a
+// handler with a sub-command.
+func (p *ArgumentParser) SetDefaults(defaults map[string]interface{}) {
+	for k, v := range defaults {
+		found := false
+		for _, a := range p.args {
+			if a.Dest == k {
+				a.Default = v
+				found = true
+			}
+		}
+		if found {
+			delete(p.defaults, k)
+			continue
+		}
+		if p.defaults == nil {
+			p.defaults = make(map[string]interface{})
+		}
+		p.defaults[k] = v
+	}
+}
+
+// GetDefault gets the default value of the Dest set on its argument or with
+// SetDefaults.
+func (p *ArgumentParser) GetDefault(dest string) (interface{}, bool) {
+	for _, a := range p.args {
+		if a.Dest == dest {
+			return a.Default, a.Default != nil
+		}
+	}
+	v, ok := p.defaults[dest]
+	return v, ok
+}
+
+// Finalize registers a function that is called with the parsed Namespace
+// after defaults are applied but before bound targets are assigned.  It can
+// be used to normalize or derive values in the Namespace so that those
+// changes are reflected in the bound targets.  Finalizers are called in the
+// order they were registered and the first error aborts parsing.
+func (p *ArgumentParser) Finalize(f func(ns Namespace) error) {
+	p.finalizers = append(p.finalizers, f)
+}
+
+// computed is a value in the Namespace that's computed after parsing when
+// it's not otherwise provided.
+type computed struct {
+	dest string
+	f    func(ns Namespace) (interface{}, error)
+}
+
+// AddComputed adds a value to the Namespace under the given dest that is
+// computed after parsing if no argument provided a value for that dest.
+// Computed values are evaluated in the order they were added so later
+// computed values can depend on earlier ones.  An argument with the same
+// Dest shows that its default is computed in its help.
+func (p *ArgumentParser) AddComputed(dest string, f func(ns Namespace) (interface{}, error)) error {
+	if p.computed(dest) {
+		return errors.Errorf(
+			"redefinition of computed value: %q", dest)
+	}
+	p.computeds = append(p.computeds, computed{dest: dest, f: f})
+	return nil
+}
+
+// computed returns true if the dest has a computed value.
+func (p *ArgumentParser) computed(dest string) bool {
+	for _, c := range p.computeds {
+		if c.dest == dest {
+			return true
+		}
+	}
+	return false
+}
+
+// getOptionals gets the optional arguments in the order they were added.
+func (p *ArgumentParser) getOptionals() []*Argument {
+	args := make([]*Argument, 0, len(p.args))
+	for _, a := range p.args {
+		if a.Optional() {
+			args = append(args, a)
+		}
 	}
-	if sorted {
-		sort.Slice(args, func(i, j int) bool {
-			return strings.Compare(args[i].Dest, args[j].Dest) < 0
-		})
+	return args
+}
+
+// defaultArgs gets os.Args[1:] if no args were given unless the parser is
+// in TestMode.
+func (p *ArgumentParser) defaultArgs(args []string) []string {
+	if len(args) == 0 && p.test == nil {
+		return os.Args[1:]
 	}
 	return args
 }
 
-func (p *ArgumentParser) handleHelp(args []string) {
-	if p.NoHelp {
-		return
+// lookupEnv gets the value of an environment variable.  Parsers in TestMode
+// don't read the environment.
+func (p *ArgumentParser) lookupEnv(name string) (string, bool) {
+	if p.test != nil {
+		return "", false
 	}
-	for _, arg := range args {
-		// TODO: Handle checking for help within subcommands.  Make
-		// this more like Python's ArgumentParser in which the help
-		// argument is just another argument in the set.
-		if arg != "-h" && arg != "--help" {
-			continue
+	return os.LookupEnv(name)
+}
+
+// stdout gets the writer that the version and help are written to.
+func (p *ArgumentParser) stdout() io.Writer {
+	if p.Stdout == nil {
+		if p.test != nil {
+			return testWriter{p.test, &p.test.stdout}
 		}
-		v, err := p.FormatHelp()
-		if err != nil {
-			v = err.Error()
+		return os.Stdout
+	}
+	return p.Stdout
+}
+
+// exit ends the program with the given status through the parser's Exit
+// function.
+func (p *ArgumentParser) exit(code int) {
+	if p.Exit != nil {
+		p.Exit(code)
+		return
+	}
+	if p.test != nil {
+		p.test.exit(code)
+		return
+	}
+	os.Exit(code)
+}
+
+// stderr gets the writer that warnings are written to.
+func (p *ArgumentParser) stderr() io.Writer {
+	if p.Stderr == nil {
+		if p.test != nil {
+			return testWriter{p.test, &p.test.stderr}
 		}
-		fmt.Fprintln(os.Stderr, v)
-		os.Exit(1)
+		return os.Stderr
 	}
+	return p.Stderr
+}
+
+// warnf writes a warning prefixed with the program name.
+func (p *ArgumentParser) warnf(format string, args ...interface{}) {
+	fmt.Fprintf(p.stderr(), "%s: warning: %s\n", p.Prog, fmt.Sprintf(format, args...))
 }
 
 // FormatHelp builds the help output into a string and returns it.
@@ -262,6 +836,188 @@ func Epilog(v string) ArgumentParserOption {
 	}
 }
 
+// NoHelp configures the ArgumentParser to not add the help argument.
+func NoHelp(p *ArgumentParser) error {
+	p.NoHelp = true
+	return nil
+}
+
+// HelpOptionStrings sets the option strings of the help argument.
+func HelpOptionStrings(ops ...string) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		if len(ops) == 0 {
+			return errors.Errorf("no help option strings specified")
+		}
+		return setValue(&p.HelpOptionStrings, "HelpOptionStrings", ops)
+	}
+}
+
+// HelpStdout configures the ArgumentParser to write help to Stdout and exit
+// with status 0 when help is requested.
+func HelpStdout(p *ArgumentParser) error {
+	p.HelpStdout = true
+	return nil
+}
+
+const (
+	// ConflictError is the ConflictHandler that makes redefining an
+	// option string an error.
+	ConflictError = "error"
+
+	// ConflictResolve is the ConflictHandler that lets arguments
+	// redefine existing option strings.
+	ConflictResolve = "resolve"
+)
+
+// ConflictHandler sets how the ArgumentParser handles redefined option
+// strings.  It must be either ConflictError or ConflictResolve.
+func ConflictHandler(v string) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		switch v {
+		case ConflictError, ConflictResolve:
+		default:
+			return errors.Errorf(
+				"invalid conflict handler: %q", v)
+		}
+		return setValue(&p.ConflictHandler, "ConflictHandler", v)
+	}
+}
+
+// Aliases sets alternative names of a sub-command's parser.
+func Aliases(names ...string) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		p.Aliases = append(p.Aliases, names...)
+		return nil
+	}
+}
+
+// Parents adds parsers whose arguments are copied into the ArgumentParser.
+// Redefining any of the parents' option strings is an error.
+func Parents(parsers ...*ArgumentParser) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		for _, parent := range parsers {
+			if parent == nil {
+				return errors.Errorf("nil parent parser")
+			}
+		}
+		p.Parents = append(p.Parents, parsers...)
+		return nil
+	}
+}
+
+// Strict configures the ArgumentParser to require explicit argument types
+// and to verify bound targets when they're bound.
+func Strict(p *ArgumentParser) error {
+	p.Strict = true
+	return nil
+}
+
+// StrictDeprecation configures the ArgumentParser to fail to parse
+// arguments that were removed in its Version.
+func StrictDeprecation(p *ArgumentParser) error {
+	p.StrictDeprecation = true
+	return nil
+}
+
+// Version sets the program version printed by the version action.
+func Version(v string) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		return setValue(&p.Version, "Version", v)
+	}
+}
+
+// Stdin sets the reader that the argument parser reads prompt answers from.
+func Stdin(r io.Reader) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		p.Stdin = r
+		return nil
+	}
+}
+
+// Stdout sets the writer that the argument parser writes the version to.
+func Stdout(w io.Writer) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		p.Stdout = w
+		return nil
+	}
+}
+
+// Run sets the handler of the parser's command called by Execute.
+func Run(f RunFunc) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		p.Run = f
+		return nil
+	}
+}
+
+// Stats sets the function called with the statistics of every parse.
+func Stats(f func(stats ParseStats)) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		p.Stats = f
+		return nil
+	}
+}
+
+// ParseStats are statistics of parsing a command line.
+type ParseStats struct {
+	// Tokens is the number of tokens evaluated.
+	Tokens int
+
+	// Options is the number of option strings matched.
+	Options int
+
+	// Values is the number of values converted.
+	Values int
+
+	// Duration is the time spent evaluating the tokens.
+	Duration time.Duration
+
+	// ConvertTime is the time spent converting values with the
+	// arguments' ValueParsers and storing them with their actions.
+	ConvertTime time.Duration
+
+	// ArgumentTimes breaks ConvertTime down by the arguments' Dests.
+	ArgumentTimes map[string]time.Duration
+}
+
+// Exit sets the function called to end the program after parsing (e.g.
+// after the version is printed).
+func Exit(f func(code int)) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		p.Exit = f
+		return nil
+	}
+}
+
+// Stderr sets the writer that the argument parser writes warnings to.
+func Stderr(w io.Writer) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		p.Stderr = w
+		return nil
+	}
+}
+
+// AutoIndent configures the ArgumentParser to size the help's left column
+// to fit its arguments.
+func AutoIndent(p *ArgumentParser) error {
+	p.AutoIndent = true
+	return nil
+}
+
+// NestedNamespaces configures the ArgumentParser to store its sub-commands'
+// values in nested Namespaces.
+func NestedNamespaces(p *ArgumentParser) error {
+	p.NestedNamespaces = true
+	return nil
+}
+
+// GNUErrors configures the ArgumentParser to produce errors phrased like
+// GNU getopt_long's.
+func GNUErrors(p *ArgumentParser) error {
+	p.GNUErrors = true
+	return nil
+}
+
 func setValue(p interface{}, name string, i interface{}) error {
 	pv := reflect.ValueOf(p)
 	if pv.Kind() != reflect.Ptr {
@@ -1,8 +1,20 @@
 package argparse
 
-import "github.com/skillian/errors"
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skillian/errors"
+)
 
 type parsingState struct {
+	// ctx cancels prompts for argument values.
+	ctx context.Context
+
 	// parser is the parser whose arguments are being parsed.
 	parser *ArgumentParser
 
@@ -12,64 +24,567 @@ type parsingState struct {
 	// argi is the index of the current argument
 	argi int
 
+	// tokens are the classified args.
+	tokens []Token
+
 	// Namespace is the currently built up argument namespace.
 	ns Namespace
 
 	// posi is the index of the currently expected positional argument.
 	posi int
+
+	// terminated is set after the "--" arg is encountered.  All args
+	// after it are positional.
+	terminated bool
+
+	// known is true when unrecognized arguments should be collected into
+	// extras instead of producing an error.
+	known bool
+
+	// extras holds the unrecognized arguments when known is true.
+	extras []string
+
+	// subparser is the sub-command's parser that parsed the rest of the
+	// args, if any.
+	subparser *ArgumentParser
+
+	// stats is non-nil when the parser collects ParseStats.
+	stats *ParseStats
+
+	// command is the deepest (sub-)command's parser selected by the
+	// args.
+	command *ArgumentParser
+
+	// occurrences counts the number of times each optional argument
+	// was given.
+	occurrences map[*Argument]int
+
+	// partial is set when the args are an incomplete command line (e.g.
+	// when completing it), so arguments missing values aren't an error.
+	partial bool
+
+	// remote is set when the values came from a request (see ParseValues)
+	// instead of a terminal, so nothing is prompted for and bound targets
+	// aren't assigned.
+	remote bool
+
+	// set holds the arguments given on the command line.
+	set SetArgs
+}
+
+// parsingStatePool holds released parsingStates so that parsing many
+// command lines (e.g. one per request in a service) reuses their token
+// slices and maps instead of allocating new ones every time.
+var parsingStatePool = sync.Pool{
+	New: func() interface{} { return new(parsingState) },
+}
+
+// getParsingState gets a parsingState from the pool initialized to parse
+// the args with p.  It must be released when it is no longer needed.
+func getParsingState(p *ArgumentParser, args []string) *parsingState {
+	s := parsingStatePool.Get().(*parsingState)
+	s.init(p, args)
+	return s
+}
+
+// release resets the state and puts it back into the pool.  Its namespace
+// and extras are not reused, so they can still be used after the state is
+// released.
+func (s *parsingState) release() {
+	tokens := s.tokens
+	for i := range tokens {
+		tokens[i] = Token{}
+	}
+	occurrences := s.occurrences
+	for a := range occurrences {
+		delete(occurrences, a)
+	}
+	*s = parsingState{tokens: tokens[:0], occurrences: occurrences}
+	parsingStatePool.Put(s)
 }
 
 func (s *parsingState) init(p *ArgumentParser, args []string) {
+	s.ctx = context.Background()
 	s.parser = p
+	s.command = p
 	s.args = args
 	s.argi = 0
 	s.ns = make(Namespace)
 }
 
-func (s *parsingState) parse() error {
+// markSet records that the argument was given on the command line.
+func (s *parsingState) markSet(a *Argument) {
+	if s.set == nil {
+		s.set = make(SetArgs)
+	}
+	s.set[a] = true
+}
+
+// tokenize classifies the args into tokens.
+func (s *parsingState) tokenize() error {
 	for s.argi < len(s.args) {
 		arg := s.args[s.argi]
-		a, ok := s.parser.Optionals[arg]
+		if arg == "--" && !s.terminated {
+			s.addToken(TokenTerminator, nil)
+			s.terminated = true
+			continue
+		}
+		kind := TokenPositional
+		a, ok := s.option(arg)
 		if ok {
-			s.argi++
+			s.addToken(TokenOption, a)
+			kind = TokenValue
 		} else {
-			// TODO: Check Subparsers before checking
-			// positionals.
+			if !s.terminated {
+				ok, err := s.inlineOption(arg)
+				if err != nil {
+					return err
+				}
+				if ok {
+					continue
+				}
+			}
+			if !s.terminated && looksLikeOption(arg) && (s.known || s.parser.GNUErrors) {
+				s.addToken(TokenUnknown, nil)
+				continue
+			}
 			if s.posi >= len(s.parser.Positionals) {
 				// TODO: Return to parent parser if
 				// exists instead of producing error.
-				return errors.Errorf(
-					"unexpected argument: %q", arg)
+				s.addToken(TokenUnknown, nil)
+				continue
 			}
 			a = s.parser.Positionals[s.posi]
 			s.posi++
 		}
-		if err := s.handle(a); err != nil {
+		if err := s.addValues(kind, a); err != nil {
 			return err
 		}
 	}
-	allArgs := append(s.parser.getOptionals(false), s.parser.Positionals...)
-	for _, a := range allArgs {
+	return nil
+}
+
+// addValues adds tokens for the values of argument a at the current arg.
+func (s *parsingState) addValues(kind TokenKind, a *Argument) error {
+	start := s.argi
+	vs, err := s.getArgs(a)
+	if err != nil {
+		return err
+	}
+	for i, v := range vs {
+		s.tokens = append(s.tokens, Token{
+			Kind:     kind,
+			Value:    v,
+			Index:    start + i,
+			Argument: a,
+		})
+	}
+	return nil
+}
+
+// inlineOption tokenizes an "--option=value" arg or a cluster of short
+// flags like "-xvf", where the last flag can take the rest of the arg or
+// the following args as its values.  It returns false if arg is neither.
+func (s *parsingState) inlineOption(arg string) (bool, error) {
+	if strings.HasPrefix(arg, "--") {
+		i := strings.IndexByte(arg, '=')
+		if i < 0 {
+			return false, nil
+		}
+		a, ok := s.option(arg[:i])
+		if !ok {
+			return false, nil
+		}
+		if err := s.addInline(a, arg[:i], arg[i+1:]); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if len(arg) < 3 || arg[0] != '-' || arg[1] == '-' {
+		return false, nil
+	}
+	var flags []*Argument
+	var names []string
+	rest := ""
+	for i, r := range arg[1:] {
+		name := "-" + string(r)
+		a, ok := s.option(name)
+		if !ok {
+			return false, nil
+		}
+		flags = append(flags, a)
+		names = append(names, name)
+		if a.Nargs != 0 {
+			rest = arg[1+i+len(string(r)):]
+			break
+		}
+	}
+	last := len(flags) - 1
+	for i, a := range flags[:last] {
+		s.tokens = append(s.tokens, Token{
+			Kind:     TokenOption,
+			Value:    names[i],
+			Index:    s.argi,
+			Argument: a,
+		})
+	}
+	if rest != "" {
+		if err := s.addInline(flags[last], names[last], rest); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	s.tokens = append(s.tokens, Token{
+		Kind:     TokenOption,
+		Value:    names[last],
+		Index:    s.argi,
+		Argument: flags[last],
+	})
+	s.argi++
+	return true, s.addValues(TokenValue, flags[last])
+}
+
+// addInline adds the option and value tokens of an option whose value is
+// part of the same arg.
+func (s *parsingState) addInline(a *Argument, name, value string) error {
+	switch {
+	case a.Nargs == 0:
+		return errors.Errorf(
+			"argument %q does not take a value", a.Dest)
+	case a.Nargs > 1:
+		return errors.Errorf(
+			"argument %q takes %d values", a.Dest, a.Nargs)
+	}
+	s.tokens = append(s.tokens, Token{
+		Kind:     TokenOption,
+		Value:    name,
+		Index:    s.argi,
+		Argument: a,
+	}, Token{
+		Kind:     TokenValue,
+		Value:    value,
+		Index:    s.argi,
+		Argument: a,
+	})
+	s.argi++
+	return nil
+}
+
+// addToken adds a token for the current arg and advances to the next one.
+func (s *parsingState) addToken(kind TokenKind, a *Argument) {
+	s.tokens = append(s.tokens, Token{
+		Kind:     kind,
+		Value:    s.args[s.argi],
+		Index:    s.argi,
+		Argument: a,
+	})
+	s.argi++
+}
+
+// option gets the optional argument matched by arg, which can be one of the
+// Persistent arguments of the parser's parents.  No args match options after
+// the "--" terminator.
+func (s *parsingState) option(arg string) (*Argument, bool) {
+	if s.terminated {
+		return nil, false
+	}
+	if a, ok := s.parser.Optionals[arg]; ok {
+		return a, true
+	}
+	for p := s.parser.parent; p != nil; p = p.parent {
+		if a, ok := p.Optionals[arg]; ok && a.Persistent {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// evaluate handles the tokens' values with their arguments' actions and
+// then applies defaults.
+func (s *parsingState) evaluate() error {
+	for i := 0; i < len(s.tokens); {
+		t := s.tokens[i]
+		i++
+		switch t.Kind {
+		case TokenUnknown:
+			if s.known {
+				s.extras = append(s.extras, t.Value)
+				continue
+			}
+			return s.unexpected(t.Value)
+		case TokenTerminator:
+			continue
+		case TokenPositional:
+			// a positional argument's values aren't preceded
+			// by an option.
+			i--
+		case TokenValue:
+			return errors.Errorf(
+				"value %q at index %d does not follow an option",
+				t.Value, t.Index)
+		}
+		a := t.Argument
+		if a == nil {
+			return errors.Errorf(
+				"%v token %q at index %d has no argument",
+				t.Kind, t.Value, t.Index)
+		}
+		kind := TokenValue
+		if t.Kind == TokenPositional {
+			kind = TokenPositional
+		}
+		var vs []string
+		for ; i < len(s.tokens); i++ {
+			if s.tokens[i].Kind != kind || s.tokens[i].Argument != a {
+				break
+			}
+			vs = append(vs, s.tokens[i].Value)
+		}
+		if t.Kind == TokenOption {
+			if s.stats != nil {
+				s.stats.Options++
+			}
+			if s.occurrences == nil {
+				s.occurrences = make(map[*Argument]int)
+			}
+			s.occurrences[a]++
+			if err := s.checkDeprecated(a, t.Value); err != nil {
+				return err
+			}
+		}
+		if t.Kind == TokenOption && a.negation(t.Value) {
+			if err := a.Action.UpdateNamespace(a, s.ns, []interface{}{false}); err != nil {
+				return err
+			}
+			s.markSet(a)
+			continue
+		}
+		if err := s.handle(a, vs); err != nil {
+			return err
+		}
+		s.markSet(a)
+	}
+	if err := s.checkOccurrences(); err != nil {
+		return err
+	}
+	for _, a := range s.parser.args {
+		if _, ok := s.ns.Get(a); ok || a.EnvVar == "" {
+			continue
+		}
+		if err := s.handleEnvVar(a); err != nil {
+			return err
+		}
+	}
+	for _, a := range s.parser.args {
+		if _, ok := s.ns.Get(a); ok || a.Confirm == "" {
+			continue
+		}
+		if s.remote {
+			return errors.Errorf(
+				"%s requires confirmation: %s  Set the %q "+
+					"parameter to confirm.",
+				a.Dest, a.Confirm, a.Dest)
+		}
+		if err := s.parser.confirm(s.ctx, a); err != nil {
+			return err
+		}
+		if err := s.handle(a, nil); err != nil {
+			return err
+		}
+	}
+	if err := s.checkRelations(); err != nil {
+		return err
+	}
+	var missing []*Argument
+	for _, a := range s.parser.args {
+		if a.subparsers != nil {
+			if s.subparser == nil && a.Default != nil {
+				if err := s.dispatch(a.subparsers, []string{stringOf(a.Default)}); err != nil {
+					return err
+				}
+			}
+			if s.subparser == nil && a.Required {
+				return errors.Errorf(
+					"missing required sub-command (choose "+
+						"from %s)",
+					strings.Join(a.subparsers.commandNames(), ", "))
+			}
+			continue
+		}
 		if _, ok := s.ns.Get(a); !ok {
 			if a.Required {
 				return errors.Errorf(
 					"missing required argument %q", a.Dest)
 			}
-			if a.Default != nil {
-				if err := a.Action.UpdateNamespace(a, s.ns, []interface{}{a.Default}); err != nil {
+			if a.RequiredIf != nil {
+				missing = append(missing, a)
+			}
+			def := a.Default
+			if def == nil && a.DefaultFunc != nil {
+				var err error
+				if def, err = a.DefaultFunc(); err != nil {
+					return errors.ErrorfWithCause(
+						err, "failed to get default of %q",
+						a.Dest)
+				}
+			}
+			if def != nil && def != Suppress {
+				if err := a.Action.UpdateNamespace(a, s.ns, []interface{}{def}); err != nil {
 					return err
 				}
 			}
 		}
 	}
+	// conditions are checked after all the defaults are applied so that
+	// they can depend on other arguments' defaults.
+	for _, a := range missing {
+		if a.RequiredIf(s.ns) {
+			return errors.Errorf(
+				"missing required argument %q", a.Dest)
+		}
+	}
 	return nil
 }
 
-func (s *parsingState) handle(a *Argument) error {
-	args, err := s.getArgs(a)
-	if err != nil {
-		return err
+// typeSlices converts the []interface{} values of arguments with an ElemType
+// into slices of their values' type.
+func (s *parsingState) typeSlices() error {
+	for _, a := range s.parser.args {
+		if !a.typedSlice {
+			continue
+		}
+		vs, ok := s.ns[a.Dest].([]interface{})
+		if !ok {
+			continue
+		}
+		et, ok := a.valueType()
+		if !ok {
+			if len(vs) == 0 {
+				continue
+			}
+			et = reflect.TypeOf(vs[0])
+		}
+		sv := reflect.MakeSlice(reflect.SliceOf(et), len(vs), len(vs))
+		for i, v := range vs {
+			rv := reflect.ValueOf(v)
+			if !rv.IsValid() || !rv.Type().AssignableTo(et) {
+				return errors.Errorf(
+					"value %v (type: %T) of argument %q "+
+						"is not a %v", v, v, a.Dest, et)
+			}
+			sv.Index(i).Set(rv)
+		}
+		s.ns[a.Dest] = sv.Interface()
+	}
+	return nil
+}
+
+// checkOccurrences checks the number of times that the optional arguments
+// were given against their MinOccurrences and MaxOccurrences.
+func (s *parsingState) checkOccurrences() error {
+	for _, a := range s.parser.args {
+		n := s.occurrences[a]
+		if a.MaxOccurrences > 0 && n > a.MaxOccurrences {
+			return errors.Errorf(
+				"argument %s: given %d times but it may be "+
+					"given at most %d times",
+				a.usageName(), n, a.MaxOccurrences)
+		}
+		if n < a.MinOccurrences {
+			return errors.Errorf(
+				"argument %s: given %d times but it must be "+
+					"given at least %d times",
+				a.usageName(), n, a.MinOccurrences)
+		}
+	}
+	return nil
+}
+
+// checkRelations checks the Requires and ConflictsWith constraints between
+// the given arguments.  It must be called before defaults are applied so
+// that only the arguments that were actually given are checked.
+func (s *parsingState) checkRelations() error {
+	for _, a := range s.parser.args {
+		if _, ok := s.ns.Get(a); !ok {
+			continue
+		}
+		for _, o := range a.requires {
+			if _, ok := s.ns.Get(o); !ok {
+				return errors.Errorf(
+					"argument %s: requires argument %s",
+					a.usageName(), o.usageName())
+			}
+		}
+		for _, o := range a.conflicts {
+			if _, ok := s.ns.Get(o); ok {
+				return errors.Errorf(
+					"argument %s: not allowed with "+
+						"argument %s",
+					a.usageName(), o.usageName())
+			}
+		}
 	}
+	return nil
+}
+
+// unexpected creates the error for an unrecognized argument.
+func (s *parsingState) unexpected(arg string) error {
+	if s.parser.GNUErrors {
+		if looksLikeOption(arg) {
+			return s.unrecognizedOption(arg)
+		}
+		return s.gnuErrorf("unexpected argument '%s'", arg)
+	}
+	return errors.Errorf("unexpected argument: %q", arg)
+}
+
+// handleEnvVar handles the value of a's environment variable, if it is set.
+func (s *parsingState) handleEnvVar(a *Argument) error {
+	v, ok := s.parser.lookupEnv(a.EnvVar)
+	if !ok {
+		return nil
+	}
+	var vs []string
+	switch a.Nargs {
+	case 0:
+		if b, err := Bool(v); err == nil && b == false {
+			return nil
+		}
+	case 1, ZeroOrOne:
+		vs = []string{v}
+	default:
+		vs = strings.Fields(v)
+	}
+	if err := s.handle(a, vs); err != nil {
+		return errors.ErrorfWithCause(
+			err, "invalid value of environment variable %s",
+			a.EnvVar)
+	}
+	return nil
+}
+
+func (s *parsingState) handle(a *Argument, args []string) error {
+	if a.subparsers != nil {
+		return s.dispatch(a.subparsers, args)
+	}
+	if s.stats == nil {
+		return s.handleValues(a, args)
+	}
+	start := time.Now()
+	err := s.handleValues(a, args)
+	d := time.Since(start)
+	s.stats.Values += len(args)
+	s.stats.ConvertTime += d
+	if s.stats.ArgumentTimes == nil {
+		s.stats.ArgumentTimes = make(map[string]time.Duration)
+	}
+	s.stats.ArgumentTimes[a.Dest] += d
+	return err
+}
+
+// handleValues converts the args with the argument's Type and updates the
+// namespace with its Action.
+func (s *parsingState) handleValues(a *Argument, args []string) error {
 	switch a.Nargs {
 	case 0:
 		if len(args) != 0 {
@@ -109,6 +624,13 @@ func (s *parsingState) handle(a *Argument) error {
 func (s *parsingState) getArgs(a *Argument) ([]string, error) {
 	r := s.remainder()
 	if a.Nargs > len(r) {
+		if s.partial {
+			s.argi += len(r)
+			return r, nil
+		}
+		if s.parser.GNUErrors && a.Optional() && s.argi > 0 {
+			return nil, s.requiresArgument(s.args[s.argi-1])
+		}
 		return nil, errors.Errorf(
 			"not enough values for argument %q", a.Dest)
 	}
@@ -116,14 +638,22 @@ func (s *parsingState) getArgs(a *Argument) ([]string, error) {
 	case 0:
 		return nil, nil
 	case ZeroOrOne:
-		if len(r) > 0 {
-			if _, ok := s.parser.Optionals[r[0]]; ok {
-				return nil, nil
-			}
+		if s.valueCount(a, r) > 0 {
 			s.argi++
 			return r[:1], nil
 		}
 		return nil, nil
+	case Parser:
+		if len(r) == 0 {
+			if s.partial {
+				return nil, nil
+			}
+			return nil, errors.Errorf(
+				"expected at least one value for argument %q",
+				a.Dest)
+		}
+		s.argi += len(r)
+		return r, nil
 	case ZeroOrMore:
 		if len(r) == 0 {
 			return nil, nil
@@ -131,16 +661,14 @@ func (s *parsingState) getArgs(a *Argument) ([]string, error) {
 		fallthrough
 	case OneOrMore:
 		if len(r) == 0 {
+			if s.partial {
+				return nil, nil
+			}
 			return nil, errors.Errorf(
 				"expected at least one value for argument %q",
 				a.Dest)
 		}
-		i := 0
-		for ; i < len(r); i++ {
-			if _, ok := s.parser.Optionals[r[i]]; ok {
-				break
-			}
-		}
+		i := s.valueCount(a, r)
 		s.argi += i
 		return r[:i], nil
 	default:
@@ -149,6 +677,86 @@ func (s *parsingState) getArgs(a *Argument) ([]string, error) {
 	}
 }
 
+// valueCount gets the number of values at the beginning of r that argument a
+// can take: All the values up to the next option string except for those
+// needed by the positional arguments after a, similar to how Python's
+// argparse matches positional arguments by pattern.
+func (s *parsingState) valueCount(a *Argument, r []string) int {
+	limit := 0
+	for ; limit < len(r); limit++ {
+		if r[limit] == "--" && !s.terminated {
+			break
+		}
+		if _, ok := s.option(r[limit]); ok {
+			break
+		}
+	}
+	if a.Optional() {
+		return limit
+	}
+	n := limit
+	for _, p := range s.parser.Positionals[s.posi:] {
+		n -= minNargs(p.Nargs)
+	}
+	if min := minNargs(a.Nargs); n < min {
+		n = min
+	}
+	if n > limit {
+		n = limit
+	}
+	return n
+}
+
+// minNargs gets the minimum number of values that an argument with the
+// given Nargs requires.
+func minNargs(nargs int) int {
+	switch nargs {
+	case ZeroOrOne, ZeroOrMore:
+		return 0
+	case OneOrMore, Parser:
+		return 1
+	default:
+		return nargs
+	}
+}
+
+// gnuErrorf creates an error prefixed with the program name, the way GNU
+// getopt_long formats its errors.  The error doesn't include a stack trace
+// so that its message is exactly what's formatted.
+func (s *parsingState) gnuErrorf(format string, args ...interface{}) error {
+	return errors.New(s.parser.Prog + ": " + fmt.Sprintf(format, args...))
+}
+
+// unrecognizedOption creates the GNU getopt_long error for an unrecognized
+// option.
+func (s *parsingState) unrecognizedOption(arg string) error {
+	if strings.HasPrefix(arg, "--") {
+		return s.gnuErrorf("unrecognized option '%s'", arg)
+	}
+	return s.gnuErrorf("invalid option -- '%s'", arg[1:2])
+}
+
+// requiresArgument creates the GNU getopt_long error for an option missing
+// its value.
+func (s *parsingState) requiresArgument(opt string) error {
+	if strings.HasPrefix(opt, "--") {
+		return s.gnuErrorf("option '%s' requires an argument", opt)
+	}
+	return s.gnuErrorf("option requires an argument -- '%s'", opt[1:])
+}
+
+// looksLikeOption returns true if the arg looks like an option string (i.e.
+// it starts with '-' and isn't just "-" or a negative number).
+func looksLikeOption(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	if c := arg[1]; c >= '0' && c <= '9' || c == '.' {
+		return false
+	}
+	return true
+}
+
 // remainder gets the remaining args or nil if there are no remaining args.
 func (s *parsingState) remainder() []string {
 	if s.argi >= len(s.args) {
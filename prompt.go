@@ -0,0 +1,205 @@
+package argparse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skillian/errors"
+)
+
+// ErrPromptCancelled is returned when a prompt is cancelled by its context
+// or by an interrupt signal before it is answered.
+var ErrPromptCancelled = errors.New("prompt cancelled")
+
+// stdin gets the reader that prompt answers are read from.
+func (p *ArgumentParser) stdin() io.Reader {
+	if p.Stdin == nil {
+		if p.test != nil {
+			return strings.NewReader("")
+		}
+		return os.Stdin
+	}
+	return p.Stdin
+}
+
+// interactive returns true if prompts can be answered.  If Stdin was
+// replaced with something other than a file, it is assumed to provide
+// answers.
+func (p *ArgumentParser) interactive() bool {
+	f, ok := p.stdin().(*os.File)
+	if !ok {
+		return true
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// prompt writes the prompt to Stderr and reads a line from Stdin.  If ctx
+// is done or an interrupt signal is received before the line is read,
+// ErrPromptCancelled is returned.
+func (p *ArgumentParser) prompt(ctx context.Context, prompt string) (string, error) {
+	if p.test == nil {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+	}
+	fmt.Fprint(p.stderr(), prompt)
+	line, err := p.lineReader().readLine(ctx)
+	if err == ErrPromptCancelled {
+		fmt.Fprintln(p.stderr())
+	}
+	return line, err
+}
+
+// stdinLines reads the answers to prompts from os.Stdin for every parser
+// so that a line left by one parser's cancelled prompt goes to the next
+// prompt of any parser.
+var stdinLines = &lineReader{r: os.Stdin}
+
+// linesMu guards the creation of the parsers' lineReaders.
+var linesMu sync.Mutex
+
+// lineReader gets the lineReader of the parser's Stdin.
+func (p *ArgumentParser) lineReader() *lineReader {
+	r := p.stdin()
+	if r == io.Reader(os.Stdin) {
+		return stdinLines
+	}
+	if p.Stdin == nil {
+		// the empty Stdin of TestMode
+		return &lineReader{r: r}
+	}
+	linesMu.Lock()
+	defer linesMu.Unlock()
+	if p.lines == nil {
+		p.lines = &lineReader{r: r}
+	}
+	return p.lines
+}
+
+// lineReader reads the answers to prompts from a reader one line at a time.
+// Only one line is read at a time.  When a prompt is cancelled, the read is
+// interrupted if the reader supports read deadlines (like the os.Files of
+// terminals and pipes) and any part of the line read so far is kept for the
+// next read.  Other readers' reads cannot be interrupted, so the line that
+// they read goes to the next prompt instead of being lost.
+type lineReader struct {
+	r io.Reader
+
+	// mu is held while a line is being read.
+	mu sync.Mutex
+
+	// pending receives the result of the read in progress (or of the
+	// read that finished after its prompt was cancelled).  It is nil if
+	// no line is being read.
+	pending chan lineResult
+
+	// partial holds the bytes of the line read before a read was
+	// interrupted.  Only the goroutine reading the line uses it.
+	partial []byte
+}
+
+type lineResult struct {
+	line string
+	err  error
+}
+
+// readDeadliner is implemented by readers whose reads can be interrupted.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// readLine reads a line.  If ctx is done before the line is read,
+// ErrPromptCancelled is returned.
+func (lr *lineReader) readLine(ctx context.Context) (string, error) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	if lr.pending == nil {
+		results := make(chan lineResult, 1)
+		lr.pending = results
+		go func() {
+			line, err := lr.read()
+			results <- lineResult{line, err}
+		}()
+	}
+	select {
+	case r := <-lr.pending:
+		lr.pending = nil
+		return r.line, r.err
+	case <-ctx.Done():
+	}
+	d, ok := lr.r.(readDeadliner)
+	if !ok || d.SetReadDeadline(time.Now()) != nil {
+		return "", ErrPromptCancelled
+	}
+	r := <-lr.pending
+	lr.pending = nil
+	if err := d.SetReadDeadline(time.Time{}); err != nil {
+		return "", err
+	}
+	if !os.IsTimeout(r.err) {
+		// the line was read before the read was interrupted.
+		results := make(chan lineResult, 1)
+		results <- r
+		lr.pending = results
+	}
+	return "", ErrPromptCancelled
+}
+
+// read reads a line from r one byte at a time so that nothing after the
+// line is consumed from r.
+func (lr *lineReader) read() (string, error) {
+	var b [1]byte
+	for {
+		n, err := lr.r.Read(b[:])
+		if n == 1 {
+			if b[0] == '\n' {
+				line := string(lr.partial)
+				lr.partial = lr.partial[:0]
+				return line, nil
+			}
+			lr.partial = append(lr.partial, b[0])
+		}
+		if err != nil {
+			if err == io.EOF && len(lr.partial) > 0 {
+				line := string(lr.partial)
+				lr.partial = lr.partial[:0]
+				return line, nil
+			}
+			return "", err
+		}
+	}
+}
+
+// confirm asks the user to confirm a's Confirm prompt and returns an error
+// if it's not confirmed.
+func (p *ArgumentParser) confirm(ctx context.Context, a *Argument) error {
+	if !p.interactive() {
+		return errors.Errorf(
+			"%s requires confirmation: %s  Pass %s to confirm "+
+				"when not running interactively.",
+			a.Dest, a.Confirm, getShortestArgOptionString(a))
+	}
+	answer, err := p.prompt(ctx, a.Confirm+" [y/N] ")
+	if err == ErrPromptCancelled {
+		return err
+	}
+	if err != nil {
+		return errors.ErrorfWithCause(
+			err, "failed to read confirmation of %s", a.Dest)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errors.Errorf("%s was not confirmed", a.Dest)
+}
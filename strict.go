@@ -0,0 +1,128 @@
+package argparse
+
+import (
+	"math/big"
+	"net"
+	"reflect"
+	"text/template"
+	"time"
+
+	"github.com/skillian/errors"
+)
+
+// valueParserTypes maps the built-in ValueParsers' function pointers to the
+// types of the values that they produce.
+var valueParserTypes = func() map[uintptr]reflect.Type {
+	m := make(map[uintptr]reflect.Type)
+	for _, p := range []struct {
+		f ValueParser
+		v interface{}
+	}{
+		{Bool, false},
+		{Float32, float32(0)},
+		{Float64, float64(0)},
+		{Int, int(0)},
+		{Int8, int8(0)},
+		{Int16, int16(0)},
+		{Int32, int32(0)},
+		{Int64, int64(0)},
+		{Uint, uint(0)},
+		{Uint8, uint8(0)},
+		{Uint16, uint16(0)},
+		{Uint32, uint32(0)},
+		{Uint64, uint64(0)},
+		{String, ""},
+		{RelativeTime, time.Time{}},
+		{GeoCoordinate, LatLon{}},
+		{Digest, Checksum{}},
+		{LanguageTag, ""},
+		{MIMEType, ""},
+		{FileExtension, ""},
+		{Rate, Throughput{}},
+		{GoTemplate, (*template.Template)(nil)},
+		{Fields, FieldSelection{}},
+		{IP, net.IP{}},
+		{CIDR, (*net.IPNet)(nil)},
+		{ByteSize, int64(0)},
+		{UUID, ""},
+		{UUIDBytes, [16]byte{}},
+		{HexBytes, []byte(nil)},
+		{Base64Bytes, []byte(nil)},
+		{ExistingFile, ""},
+		{ExistingDir, ""},
+		{WritablePath, ""},
+		{Glob, []string(nil)},
+		{BigInt, (*big.Int)(nil)},
+		{BigRat, (*big.Rat)(nil)},
+	} {
+		m[reflect.ValueOf(p.f).Pointer()] = reflect.TypeOf(p.v)
+	}
+	return m
+}()
+
+// valueType gets the type of the individual values of the argument, if it
+// can be determined without parsing anything.
+func (a *Argument) valueType() (reflect.Type, bool) {
+	if a.Nargs == 0 {
+		if a.Const == nil {
+			return nil, false
+		}
+		return reflect.TypeOf(a.Const), true
+	}
+	if a.Choices != nil && a.Choices.Len() > 0 {
+		return reflect.TypeOf(a.Choices.At(0).Value), true
+	}
+	if a.typ != nil {
+		return a.typ, true
+	}
+	if a.Type != nil {
+		if t, ok := valueParserTypes[reflect.ValueOf(a.Type).Pointer()]; ok {
+			return t, true
+		}
+	}
+	if a.Default != nil && a.Default != Suppress {
+		return reflect.TypeOf(a.Default), true
+	}
+	return nil, false
+}
+
+// checkTarget verifies that values of the argument can be assigned to a
+// bound target of type tt.
+func (a *Argument) checkTarget(tt reflect.Type) error {
+	vt, ok := a.valueType()
+	if !ok {
+		return errors.Errorf(
+			"cannot determine the type of %q's values to verify "+
+				"its bound target of type %v.  Use one of "+
+				"the built-in ValueParsers or set a Default",
+			a.Dest, tt)
+	}
+	if compatibleType(vt, tt) {
+		return nil
+	}
+	if tt.Kind() == reflect.Slice && compatibleType(vt, tt.Elem()) {
+		return nil
+	}
+	if tt.Kind() == reflect.Map && tt.Key().Kind() == reflect.String &&
+		(compatibleType(vt, tt.Elem()) || vt.Kind() == reflect.String) {
+		// StoreMap values or "key=value" strings parsed when bound.
+		return nil
+	}
+	return errors.Errorf(
+		"%q's values of type %v cannot be bound to a target of "+
+			"type %v%s",
+		a.Dest, vt, tt, parserHint(tt))
+}
+
+// compatibleType checks if values of type vt can be assigned to targets of
+// type tt.  Numbers are convertible to strings as runes, but that's never
+// what's meant when binding an argument, so it's not allowed.
+func compatibleType(vt, tt reflect.Type) bool {
+	if vt.AssignableTo(tt) {
+		return true
+	}
+	if tt.Kind() == reflect.String && vt.Kind() != reflect.String {
+		return false
+	}
+	return vt.ConvertibleTo(tt)
+}
@@ -0,0 +1,184 @@
+package argparse
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/skillian/errors"
+)
+
+// ParseInto adds arguments for the tagged fields of the struct that target
+// points to (see AddStructArguments), parses the args into the fields and
+// returns the namespace.
+func (p *ArgumentParser) ParseInto(target interface{}, args ...string) (Namespace, error) {
+	if err := p.AddStructArguments(target); err != nil {
+		return nil, err
+	}
+	return p.ParseArgs(args...)
+}
+
+// AddStructArguments adds an argument for every field of the struct that
+// target points to with an "arg" tag and binds the argument to the field.
+// The arg tag holds the comma-separated option strings of an optional
+// argument (e.g. `arg:"-c,--count"`) or the Dest of a positional argument
+// (e.g. `arg:"files"`).  These tags configure the argument further:
+//
+//	help      the argument's Help
+//	default   the argument's Default, parsed like a value from the
+//	          command line
+//	metavar   the argument's MetaVar
+//	env       the argument's EnvVar
+//	required  "true" if the argument is Required
+//
+// The argument's Type is chosen from the field's type.  Bool fields are
+// flags that store true, slice fields are appended to (or take one or more
+// values if positional) and time.Duration fields are parsed with
+// time.ParseDuration.  Fields of types that implement
+// encoding.TextUnmarshaler are unmarshaled.  The fields of untagged struct
+// fields are added too.
+func (p *ArgumentParser) AddStructArguments(target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.Errorf(
+			"target must be a pointer to a struct, not %T", target)
+	}
+	return p.addStructArguments(v.Elem())
+}
+
+// MustAddStructArguments panics if the struct's arguments cannot be added.
+func (p *ArgumentParser) MustAddStructArguments(target interface{}) {
+	if err := p.AddStructArguments(target); err != nil {
+		panic(err)
+	}
+}
+
+func (p *ArgumentParser) addStructArguments(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+		tag, ok := sf.Tag.Lookup("arg")
+		if !ok {
+			if sf.Type.Kind() == reflect.Struct && !textUnmarshalable(sf.Type) {
+				if err := p.addStructArguments(fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if tag == "-" {
+			continue
+		}
+		a, err := p.AddArgument(structFieldOptions(sf, tag)...)
+		if err != nil {
+			return errors.ErrorfWithCause(
+				err, "failed to add argument for field %v.%s",
+				t, sf.Name)
+		}
+		if err := p.boundArgs.bindValue(a, fv); err != nil {
+			return errors.ErrorfWithCause(
+				err, "failed to bind field %v.%s", t, sf.Name)
+		}
+	}
+	return nil
+}
+
+// structFieldOptions gets the options of the argument of a struct field.
+func structFieldOptions(sf reflect.StructField, tag string) []ArgumentOption {
+	var opts []ArgumentOption
+	names := strings.Split(tag, ",")
+	optional := strings.HasPrefix(names[0], "-")
+	if optional {
+		opts = append(opts, OptionStrings(names...))
+	} else {
+		opts = append(opts, Dest(names[0]))
+	}
+	ft := sf.Type
+	slice := ft.Kind() == reflect.Slice && !textUnmarshalable(ft)
+	switch {
+	case ft.Kind() == reflect.Bool:
+		opts = append(opts, ActionFunc(StoreTrue))
+	case slice && optional:
+		opts = append(opts, ActionFunc(Append), Nargs(1))
+	case slice:
+		opts = append(opts, Nargs(OneOrMore))
+	case optional:
+		opts = append(opts, ActionFunc(Store))
+	default:
+		opts = append(opts, Nargs(1))
+	}
+	et := ft
+	if slice {
+		et = ft.Elem()
+	}
+	if typ, ok := structFieldType(et); ok {
+		opts = append(opts, typ)
+	}
+	if help, ok := sf.Tag.Lookup("help"); ok {
+		opts = append(opts, Help("%s", help))
+	}
+	if def, ok := sf.Tag.Lookup("default"); ok {
+		if ft.Kind() == reflect.Bool {
+			opts = append(opts, func(a *Argument) error {
+				v, err := Bool(def)
+				if err != nil {
+					return err
+				}
+				a.Default = v
+				return nil
+			})
+		} else {
+			opts = append(opts, Default(def))
+		}
+	}
+	if mv, ok := sf.Tag.Lookup("metavar"); ok {
+		opts = append(opts, MetaVar(mv))
+	}
+	if env, ok := sf.Tag.Lookup("env"); ok {
+		opts = append(opts, EnvVar(env))
+	}
+	if req := sf.Tag.Get("required"); req == "true" {
+		opts = append(opts, Required)
+	}
+	return opts
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// structFieldParsers are the ValueParsers of struct fields' kinds.
+var structFieldParsers = map[reflect.Kind]ValueParser{
+	reflect.Float32: Float32,
+	reflect.Float64: Float64,
+	reflect.Int:     Int,
+	reflect.Int8:    Int8,
+	reflect.Int16:   Int16,
+	reflect.Int32:   Int32,
+	reflect.Int64:   Int64,
+	reflect.Uint:    Uint,
+	reflect.Uint8:   Uint8,
+	reflect.Uint16:  Uint16,
+	reflect.Uint32:  Uint32,
+	reflect.Uint64:  Uint64,
+	reflect.String:  String,
+}
+
+// structFieldType gets the option that sets the Type of arguments with
+// values of type t.  Types that implement encoding.TextUnmarshaler get the
+// String Type so that they're unmarshaled when they're bound.
+func structFieldType(t reflect.Type) (ArgumentOption, bool) {
+	if t == durationType {
+		return TypeFunc(time.ParseDuration), true
+	}
+	if textUnmarshalable(t) {
+		return Type(String), true
+	}
+	parse, ok := structFieldParsers[t.Kind()]
+	if !ok {
+		return nil, false
+	}
+	return Type(parse), true
+}
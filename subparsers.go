@@ -0,0 +1,318 @@
+package argparse
+
+import (
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+// Subparsers is the collection of sub-commands of an ArgumentParser.  The
+// first value of the Subparsers' argument selects the sub-parser that parses
+// the rest of the command line and the sub-parser's namespace is merged into
+// the parent parser's namespace.
+type Subparsers struct {
+	// Argument is the positional argument whose values are parsed by the
+	// sub-parsers.  If its Dest is set, the name of the selected
+	// sub-command is stored in the namespace under that key.
+	*Argument
+
+	// parsers holds the sub-parsers in the order they were added.
+	parsers []*ArgumentParser
+
+	// names maps the sub-commands' names to their parsers.
+	names map[string]*ArgumentParser
+
+	// metaVar is true when the MetaVar was set explicitly so it isn't
+	// overwritten with the sub-commands' names.
+	metaVar bool
+}
+
+// AddSubparsers adds the sub-commands argument to the parser.  The options
+// configure the argument like any other positional argument (e.g. with Help).
+// If the Dest option is given, the selected sub-command's name is stored
+// in the namespace under it and with the Required option, parsing fails
+// if no sub-command is given.  The Default option names the sub-command
+// that parses the (empty) rest of the command line when no sub-command is
+// given.  A parser can only have one set of
+// Subparsers.
+func (p *ArgumentParser) AddSubparsers(options ...ArgumentOption) (*Subparsers, error) {
+	if p.subparsers != nil {
+		return nil, errors.Errorf(
+			"parser %q already has subparsers", p.Prog)
+	}
+	ss := &Subparsers{names: make(map[string]*ArgumentParser)}
+	opts := make([]ArgumentOption, 0, len(options)+3)
+	opts = append(opts, ActionFunc(Store), Nargs(Parser))
+	opts = append(opts, options...)
+	opts = append(opts, func(a *Argument) error {
+		if a.Optional() {
+			return errors.Errorf(
+				"subparsers cannot have option strings")
+		}
+		ss.metaVar = len(a.MetaVar) > 0
+		a.subparsers = ss
+		if a.Type == nil {
+			// the values are sub-commands' names and their
+			// args, so Strict parsers don't need a Type.
+			a.Type = String
+		}
+		return nil
+	})
+	a, err := p.AddArgument(opts...)
+	if err != nil {
+		return nil, err
+	}
+	ss.Argument = a
+	p.subparsers = ss
+	ss.setMetaVar()
+	return ss, nil
+}
+
+// MustAddSubparsers adds the sub-commands argument to the parser or panics
+// if it cannot be added.
+func (p *ArgumentParser) MustAddSubparsers(options ...ArgumentOption) *Subparsers {
+	ss, err := p.AddSubparsers(options...)
+	if err != nil {
+		panic(err)
+	}
+	return ss
+}
+
+// AddParser adds a sub-command's parser.  The sub-parser's Prog defaults to
+// the parent's Prog followed by the name so that the usage of commands nested
+// at any depth shows the full command (e.g. "tool remote add").  Sub-parsers
+// can have their own Subparsers to build deeper command trees.
+func (ss *Subparsers) AddParser(name string, options ...ArgumentParserOption) (*ArgumentParser, error) {
+	if _, ok := ss.names[name]; ok {
+		return nil, errors.Errorf(
+			"redefinition of sub-command: %q", name)
+	}
+	opts := make([]ArgumentParserOption, 0, len(options)+1)
+	opts = append(opts, Prog(ss.parser.Prog+" "+name))
+	opts = append(opts, options...)
+	sp, err := NewArgumentParser(opts...)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "failed to create sub-command %q", name)
+	}
+	for _, alias := range sp.Aliases {
+		if _, ok := ss.names[alias]; ok || alias == name {
+			return nil, errors.Errorf(
+				"alias %q of sub-command %q is already "+
+					"defined", alias, name)
+		}
+	}
+	sp.name = name
+	sp.parent = ss.parser
+	sp.inheritSettings(ss.parser)
+	ss.names[name] = sp
+	for _, alias := range sp.Aliases {
+		ss.names[alias] = sp
+	}
+	ss.parsers = append(ss.parsers, sp)
+	ss.parser.Subparsers = append(ss.parser.Subparsers, sp)
+	ss.setMetaVar()
+	return sp, nil
+}
+
+// inheritSettings copies the settings of the parent parser that the sub-parser
+// didn't set itself so that, e.g., the help of a sub-command is written to
+// the same Stderr and ends the program with the same Exit.
+func (p *ArgumentParser) inheritSettings(parent *ArgumentParser) {
+	if p.test == nil {
+		p.test = parent.test
+	}
+	if p.Exit == nil {
+		p.Exit = parent.Exit
+	}
+	if p.Stdout == nil {
+		p.Stdout = parent.Stdout
+	}
+	if p.Stderr == nil {
+		p.Stderr = parent.Stderr
+	}
+	if p.HelpTheme == "" {
+		p.HelpTheme = parent.HelpTheme
+	}
+	p.HelpStdout = p.HelpStdout || parent.HelpStdout
+	p.GNUErrors = p.GNUErrors || parent.GNUErrors
+	p.Strict = p.Strict || parent.Strict
+	p.StrictDeprecation = p.StrictDeprecation || parent.StrictDeprecation
+}
+
+// MustAddParser adds a sub-command's parser or panics if it cannot be
+// created.
+func (ss *Subparsers) MustAddParser(name string, options ...ArgumentParserOption) *ArgumentParser {
+	sp, err := ss.AddParser(name, options...)
+	if err != nil {
+		panic(err)
+	}
+	return sp
+}
+
+// setMetaVar sets the MetaVar to the sub-commands' names, e.g. {add,rm},
+// unless it was set explicitly.
+func (ss *Subparsers) setMetaVar() {
+	if ss.metaVar {
+		return
+	}
+	ss.MetaVar = []string{"{" + strings.Join(ss.commandNames(), ",") + "}", "..."}
+}
+
+// commandNames gets the names (not including aliases) of the sub-commands
+// in the order they were added.
+func (ss *Subparsers) commandNames() []string {
+	names := make([]string, len(ss.parsers))
+	for i, sp := range ss.parsers {
+		names[i] = sp.name
+	}
+	return names
+}
+
+// commandLabel gets the sub-command's name along with its aliases, e.g.
+// "remove (rm, del)".
+func commandLabel(sp *ArgumentParser) string {
+	if len(sp.Aliases) == 0 {
+		return sp.name
+	}
+	return sp.name + " (" + strings.Join(sp.Aliases, ", ") + ")"
+}
+
+// dispatch parses the args after the sub-command name with the selected
+// sub-parser and merges its namespace into s's.
+func (s *parsingState) dispatch(ss *Subparsers, args []string) error {
+	if len(args) == 0 {
+		return errors.Errorf("expected a sub-command")
+	}
+	name := args[0]
+	sp, ok := ss.names[name]
+	if !ok {
+		if suggestion, ok := ss.suggest(name); ok {
+			return errors.Errorf(
+				"invalid sub-command %q: did you mean %q?",
+				name, suggestion)
+		}
+		return errors.Errorf(
+			"invalid sub-command %q (choose from %s)",
+			name, strings.Join(ss.commandNames(), ", "))
+	}
+	s.subparser = sp
+	if ss.Dest != "" {
+		// the name and not the alias is stored so that code
+		// switching on the command doesn't need to know the aliases.
+		s.ns[ss.Dest] = sp.name
+	}
+	sub := getParsingState(sp, args[1:])
+	sub.ctx, sub.known, sub.remote = s.ctx, s.known, s.remote
+	// persistent arguments given before the sub-command are continued
+	// by the sub-command so that e.g. Append accumulates across both.
+	for _, a := range sp.persistentArgs() {
+		if v, ok := s.ns[a.Dest]; ok {
+			sub.ns[a.Dest] = v
+		}
+	}
+	if err := sp.parse(sub); err != nil {
+		sub.release()
+		return err
+	}
+	if s.parser.NestedNamespaces {
+		s.nest(sp, sub.ns)
+	} else {
+		for k, v := range sub.ns {
+			s.ns[k] = v
+		}
+	}
+	for a := range sub.set {
+		s.markSet(a)
+	}
+	s.extras = append(s.extras, sub.extras...)
+	s.command = sub.command
+	sub.release()
+	return nil
+}
+
+// nest stores the namespace of the sub-command sp under its name except for
+// the values of persistent arguments, which belong to s's namespace.
+func (s *parsingState) nest(sp *ArgumentParser, ns Namespace) {
+	for _, a := range sp.persistentArgs() {
+		if v, ok := ns[a.Dest]; ok {
+			s.ns[a.Dest] = v
+			delete(ns, a.Dest)
+		}
+	}
+	s.ns[sp.name] = ns
+}
+
+// persistentArgs gets the Persistent arguments of the parser's parents that
+// can be given to the parser.
+func (p *ArgumentParser) persistentArgs() (args []*Argument) {
+	for parent := p.parent; parent != nil; parent = parent.parent {
+		for _, a := range parent.getOptionals() {
+			if !a.Persistent {
+				continue
+			}
+			shadowed := false
+			for _, op := range a.OptionStrings {
+				if _, ok := p.Optionals[op]; ok {
+					shadowed = true
+					break
+				}
+			}
+			if !shadowed {
+				args = append(args, a)
+			}
+		}
+	}
+	return
+}
+
+// suggest gets the sub-command name or alias closest to name if it's close
+// enough to probably be what was meant.
+func (ss *Subparsers) suggest(name string) (suggestion string, ok bool) {
+	// allow about one edit for every 3 characters, up to 3 edits:
+	limit := len(name)/3 + 1
+	if limit > 3 {
+		limit = 3
+	}
+	best := limit + 1
+	for _, sp := range ss.parsers {
+		for _, n := range append([]string{sp.name}, sp.Aliases...) {
+			if d := levenshtein(name, n); d < best {
+				best, suggestion, ok = d, n, true
+			}
+		}
+	}
+	return
+}
+
+// levenshtein computes the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,105 @@
+package argparse
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/skillian/errors"
+)
+
+// TB is the part of testing.TB that NewTestParser uses.
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// NewTestParser creates a parser in TestMode or fails the test if the
+// parser cannot be created.
+func NewTestParser(t TB, options ...ArgumentParserOption) *ArgumentParser {
+	t.Helper()
+	opts := make([]ArgumentParserOption, 0, len(options)+1)
+	opts = append(opts, TestMode)
+	opts = append(opts, options...)
+	p, err := NewArgumentParser(opts...)
+	if err != nil {
+		t.Fatalf("failed to create test parser: %v", err)
+	}
+	return p
+}
+
+// TestMode keeps the parser (and its sub-commands) from having any
+// process-wide side effects so that it can be used from parallel tests:
+//
+//   - The program is never exited.  The exit code is recorded instead.
+//   - Output that would go to os.Stdout or os.Stderr is captured.
+//   - Prompts read from an empty Stdin and don't catch interrupt signals.
+//   - os.Args and environment variables aren't read.
+//
+// Explicitly set Stdout, Stderr, Stdin and Exit options are still used.
+// What the parser captured is retrieved with TestCapture.
+func TestMode(p *ArgumentParser) error {
+	if p.test == nil {
+		p.test = new(testCapture)
+	}
+	if p.Prog == "" {
+		p.Prog = "test"
+	}
+	return nil
+}
+
+// TestCapture holds the output and exit code captured by a parser in
+// TestMode.
+type TestCapture struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Exited   bool
+}
+
+// TestCapture gets what the parser (and its sub-commands) captured in
+// TestMode.
+func (p *ArgumentParser) TestCapture() (TestCapture, error) {
+	if p.test == nil {
+		return TestCapture{}, errors.Errorf(
+			"parser %q is not in test mode", p.Prog)
+	}
+	c := p.test
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return TestCapture{
+		Stdout:   c.stdout.String(),
+		Stderr:   c.stderr.String(),
+		ExitCode: c.exitCode,
+		Exited:   c.exited,
+	}, nil
+}
+
+// testCapture holds the state of a parser in TestMode that would otherwise
+// be written to the process.
+type testCapture struct {
+	mu       sync.Mutex
+	stdout   strings.Builder
+	stderr   strings.Builder
+	exitCode int
+	exited   bool
+}
+
+// testWriter writes into one of a testCapture's builders.
+type testWriter struct {
+	c  *testCapture
+	sb *strings.Builder
+}
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.c.mu.Lock()
+	defer w.c.mu.Unlock()
+	return w.sb.Write(p)
+}
+
+func (c *testCapture) exit(code int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.exited {
+		c.exitCode, c.exited = code, true
+	}
+}
@@ -0,0 +1,158 @@
+package argparse
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+const (
+	// ThemeAuto colors the help when it's written to a terminal and picks
+	// the light or dark palette based on the terminal's background.
+	ThemeAuto = "auto"
+
+	// ThemeLight colors the help with a palette that's readable on a
+	// light background.
+	ThemeLight = "light"
+
+	// ThemeDark colors the help with a palette that's readable on a dark
+	// background.
+	ThemeDark = "dark"
+)
+
+// palette holds the ANSI escape sequences used to style the help.
+type palette struct {
+	header  string
+	option  string
+	metaVar string
+}
+
+const ansiReset = "\x1b[0m"
+
+var palettes = map[string]palette{
+	ThemeLight: {
+		header:  "\x1b[1m",
+		option:  "\x1b[34m",
+		metaVar: "\x1b[35m",
+	},
+	ThemeDark: {
+		header:  "\x1b[1m",
+		option:  "\x1b[96m",
+		metaVar: "\x1b[93m",
+	},
+}
+
+// HelpTheme enables colored help output with the given theme: ThemeAuto,
+// ThemeLight or ThemeDark.
+func HelpTheme(v string) ArgumentParserOption {
+	return func(p *ArgumentParser) error {
+		switch v {
+		case ThemeAuto, ThemeLight, ThemeDark:
+		default:
+			return errors.Errorf("invalid help theme: %q", v)
+		}
+		return setValue(&p.HelpTheme, "HelpTheme", v)
+	}
+}
+
+// helpPalette gets the palette to style help written to w.  It returns false
+// if the help shouldn't be styled.
+func (p *ArgumentParser) helpPalette(w io.Writer) (palette, bool) {
+	theme := p.HelpTheme
+	if theme == ThemeAuto {
+		if !p.colorTerminal(w) {
+			return palette{}, false
+		}
+		theme = p.terminalTheme()
+	}
+	pal, ok := palettes[theme]
+	return pal, ok
+}
+
+// colorTerminal checks if w is a terminal that colors can be written to.
+func (p *ArgumentParser) colorTerminal(w io.Writer) bool {
+	if _, ok := p.lookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	if term, _ := p.lookupEnv("TERM"); term == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalTheme guesses whether the terminal's background is light or dark
+// from the COLORFGBG environment variable that rxvt, Konsole and others set
+// (e.g. "15;0" for white on black).  Querying the terminal directly (i.e.
+// with OSC 11) needs the terminal in raw mode, so when COLORFGBG isn't set,
+// a dark background is assumed because it's the most common.
+func (p *ArgumentParser) terminalTheme() string {
+	v, ok := p.lookupEnv("COLORFGBG")
+	if !ok {
+		return ThemeDark
+	}
+	fields := strings.Split(v, ";")
+	bg, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return ThemeDark
+	}
+	// Of the 16 standard colors, white (7) and the bright colors other
+	// than bright black (8) are light.
+	if bg == 7 || (bg > 8 && bg < 16) {
+		return ThemeLight
+	}
+	return ThemeDark
+}
+
+// styleHelp styles help formatted by FormatHelp: section headers are
+// emphasized and the option strings and metavars in the argument headers
+// are colored.
+func styleHelp(help string, pal palette) string {
+	lines := strings.Split(help, "\n")
+	for i, line := range lines {
+		switch {
+		case line == "":
+		case line[0] != ' ' && strings.HasSuffix(line, ":"):
+			lines[i] = pal.header + line + ansiReset
+		case strings.HasPrefix(line, "usage: "):
+			lines[i] = pal.header + "usage:" + ansiReset + line[len("usage:"):]
+		case strings.HasPrefix(line, "  -"):
+			lines[i] = styleArgHeader(line, pal)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// styleArgHeader styles the option strings and metavars at the beginning of
+// a line of an optional argument's help.
+func styleArgHeader(line string, pal palette) string {
+	end := strings.Index(line[2:], "  ")
+	if end == -1 {
+		end = len(line)
+	} else {
+		end += 2
+	}
+	var sb strings.Builder
+	sb.WriteString(line[:2])
+	for i, word := range strings.Split(line[2:end], " ") {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		style := pal.metaVar
+		if strings.HasPrefix(word, "-") {
+			style = pal.option
+		}
+		sb.WriteString(style)
+		sb.WriteString(word)
+		sb.WriteString(ansiReset)
+	}
+	sb.WriteString(line[end:])
+	return sb.String()
+}
@@ -0,0 +1,77 @@
+package argparse_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/skillian/argparse"
+)
+
+// openPTY opens a pseudo-terminal so that ThemeAuto colors the help written
+// to it.
+func openPTY(t *testing.T) (master, slave *os.File) {
+	m, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	var unlock int32
+	if _, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL, m.Fd(), syscall.TIOCSPTLCK,
+		uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		t.Skipf("failed to unlock pseudo-terminal: %v", errno)
+	}
+	var n uint32
+	if _, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL, m.Fd(), syscall.TIOCGPTN,
+		uintptr(unsafe.Pointer(&n))); errno != 0 {
+		t.Skipf("failed to get pseudo-terminal number: %v", errno)
+	}
+	s, err := os.OpenFile(
+		fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("failed to open pseudo-terminal: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return m, s
+}
+
+func TestThemeAuto(t *testing.T) {
+	t.Setenv("TERM", "xterm")
+	t.Setenv("NO_COLOR", "")
+	os.Unsetenv("NO_COLOR")
+
+	for _, tc := range []struct {
+		colorfgbg string
+		option    string
+	}{
+		{"0;15", "\x1b[34m--help\x1b[0m"},
+		{"15;0", "\x1b[96m--help\x1b[0m"},
+		{"0;default;7", "\x1b[34m--help\x1b[0m"},
+	} {
+		t.Setenv("COLORFGBG", tc.colorfgbg)
+		master, slave := openPTY(t)
+		p := argparse.MustNewArgumentParser(
+			argparse.Prog("test"),
+			argparse.HelpTheme(argparse.ThemeAuto),
+			argparse.HelpStdout,
+			argparse.Stdout(slave),
+			argparse.Exit(func(code int) {}))
+		if _, err := p.ParseArgs("-h"); err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, 1<<16)
+		n, err := master.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if help := string(b[:n]); !strings.Contains(help, tc.option) {
+			t.Fatalf("COLORFGBG=%s: expected %q in:\n%q",
+				tc.colorfgbg, tc.option, help)
+		}
+	}
+}
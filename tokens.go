@@ -0,0 +1,55 @@
+package argparse
+
+// TokenKind classifies a Token.
+type TokenKind int
+
+const (
+	// TokenUnknown is an arg that wasn't matched to any argument.
+	TokenUnknown TokenKind = iota
+
+	// TokenOption is an option string of an optional argument.
+	TokenOption
+
+	// TokenValue is a value of the Token's optional Argument.
+	TokenValue
+
+	// TokenPositional is a value of the Token's positional Argument.
+	TokenPositional
+
+	// TokenTerminator is the "--" arg after which all args are
+	// positional.
+	TokenTerminator
+)
+
+var tokenKindNames = [...]string{
+	TokenUnknown:    "unknown",
+	TokenOption:     "option",
+	TokenValue:      "value",
+	TokenPositional: "positional",
+	TokenTerminator: "terminator",
+}
+
+// String implements fmt.Stringer.
+func (k TokenKind) String() string {
+	if k < 0 || int(k) >= len(tokenKindNames) {
+		return "invalid"
+	}
+	return tokenKindNames[k]
+}
+
+// Token is a classified command line argument.  Tokens are produced by
+// ArgumentParser.Tokenize and consumed by ArgumentParser.Evaluate.
+type Token struct {
+	// Kind is the classification of the token.
+	Kind TokenKind
+
+	// Value is the arg that the token was produced from.
+	Value string
+
+	// Index is the index of the arg within the args that were tokenized.
+	Index int
+
+	// Argument is the argument the token was matched to.  It is nil for
+	// TokenUnknown and TokenTerminator tokens.
+	Argument *Argument
+}
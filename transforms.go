@@ -0,0 +1,33 @@
+package argparse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TrimSpace removes leading and trailing white space from the value.  It is
+// meant to be used with the Transform option.
+func TrimSpace(v string) string { return strings.TrimSpace(v) }
+
+// ToLower converts the value to lower case.  It is meant to be used with the
+// Transform option.
+func ToLower(v string) string { return strings.ToLower(v) }
+
+// ExpandHome replaces a leading "~" in the value with the user's home
+// directory.  Values starting with "~user" are left as they are.  It is
+// meant to be used with the Transform option.
+func ExpandHome(v string) string {
+	if v != "~" && !strings.HasPrefix(v, "~/") && !strings.HasPrefix(v, "~"+string(filepath.Separator)) {
+		return v
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return v
+	}
+	return home + v[1:]
+}
+
+// ExpandEnv replaces $VAR and ${VAR} in the value with the values of the
+// environment variables.  It is meant to be used with the Transform option.
+func ExpandEnv(v string) string { return os.ExpandEnv(v) }
@@ -0,0 +1,1239 @@
+package argparse
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"mime"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/skillian/errors"
+)
+
+// RelativeTime converts the given string into a time.Time value relative to
+// the current time.  See RelativeTimeClock for the accepted formats.
+// It implements the ValueParser interface.
+func RelativeTime(v string) (interface{}, error) {
+	return parseRelativeTime(v, time.Now())
+}
+
+// RelativeTimeClock creates a ValueParser like RelativeTime that resolves
+// times relative to the time returned by clock so that the results can be
+// reproduced (e.g. in tests).
+//
+// The accepted formats are:
+//
+//	now, today, yesterday, tomorrow
+//	any of the above followed by +/- and an offset like 2h, 30m, 3d or 1w
+//	2006-01-02, 2006-01-02T15:04:05 or an RFC 3339 timestamp
+func RelativeTimeClock(clock func() time.Time) ValueParser {
+	return func(v string) (interface{}, error) {
+		return parseRelativeTime(v, clock())
+	}
+}
+
+// relativeTimeLayouts are the absolute time layouts accepted by
+// RelativeTime.
+var relativeTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func parseRelativeTime(v string, now time.Time) (interface{}, error) {
+	for _, layout := range relativeTimeLayouts {
+		if t, err := time.ParseInLocation(layout, v, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	base, offset := v, ""
+	if i := strings.IndexAny(v, "+-"); i != -1 {
+		base, offset = v[:i], v[i:]
+	}
+	var t time.Time
+	midnight := time.Date(
+		now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(base) {
+	case "now":
+		t = now
+	case "today":
+		t = midnight
+	case "yesterday":
+		t = midnight.AddDate(0, 0, -1)
+	case "tomorrow":
+		t = midnight.AddDate(0, 0, 1)
+	default:
+		return nil, errors.Errorf("invalid time: %q", v)
+	}
+	if offset == "" {
+		return t, nil
+	}
+	d, err := parseTimeOffset(offset[1:])
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid time offset in %q", v)
+	}
+	if offset[0] == '-' {
+		d = -d
+	}
+	return t.Add(d), nil
+}
+
+// parseTimeOffset parses a time.Duration that can also be in days ("3d") or
+// weeks ("1w").
+func parseTimeOffset(v string) (time.Duration, error) {
+	if n := len(v) - 1; n > 0 && (v[n] == 'd' || v[n] == 'w') {
+		i, err := strconv.Atoi(v[:n])
+		if err != nil {
+			return 0, err
+		}
+		d := time.Duration(i) * 24 * time.Hour
+		if v[n] == 'w' {
+			d *= 7
+		}
+		return d, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// LatLon is a geographic coordinate in decimal degrees.
+type LatLon struct {
+	Lat float64
+	Lon float64
+}
+
+// String formats the coordinate the way GeoCoordinate parses it.
+func (c LatLon) String() string {
+	return strconv.FormatFloat(c.Lat, 'f', -1, 64) + "," +
+		strconv.FormatFloat(c.Lon, 'f', -1, 64)
+}
+
+// GeoCoordinate converts the given "lat,lon" string into a LatLon value.
+// The latitude must be within [-90, 90] and the longitude within
+// [-180, 180].
+// It implements the ValueParser interface.
+func GeoCoordinate(v string) (interface{}, error) {
+	parts, err := splitComposite(v, ",", 2)
+	if err != nil {
+		return nil, err
+	}
+	var c LatLon
+	if c.Lat, err = strconv.ParseFloat(parts[0], 64); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid latitude in %q", v)
+	}
+	if c.Lon, err = strconv.ParseFloat(parts[1], 64); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid longitude in %q", v)
+	}
+	if !isFinite(c.Lat) || !isFinite(c.Lon) {
+		return nil, errors.Errorf(
+			"coordinates of %q must be finite numbers", v)
+	}
+	if c.Lat < -90 || c.Lat > 90 {
+		return nil, errors.Errorf(
+			"latitude %v of %q is not within [-90, 90]", c.Lat, v)
+	}
+	if c.Lon < -180 || c.Lon > 180 {
+		return nil, errors.Errorf(
+			"longitude %v of %q is not within [-180, 180]", c.Lon, v)
+	}
+	return c, nil
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// splitComposite splits a composite value (e.g. "lat,lon") into exactly n
+// parts separated by sep with the parts' surrounding whitespace removed.
+func splitComposite(v, sep string, n int) ([]string, error) {
+	parts := strings.Split(v, sep)
+	if len(parts) != n {
+		return nil, errors.Errorf(
+			"expected %d %q-separated parts in %q, not %d",
+			n, sep, v, len(parts))
+	}
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return parts, nil
+}
+
+// Checksum is an algorithm name and the digest that it produced.
+type Checksum struct {
+	Algorithm string
+	Sum       []byte
+}
+
+// String formats the checksum the way Digest parses it.
+func (c Checksum) String() string {
+	return c.Algorithm + ":" + hex.EncodeToString(c.Sum)
+}
+
+// digestSizes maps the digest algorithms accepted by Digest to the lengths
+// (in bytes) of their sums.
+var digestSizes = map[string]int{
+	"md5":    md5.Size,
+	"sha1":   sha1.Size,
+	"sha224": sha256.Size224,
+	"sha256": sha256.Size,
+	"sha384": sha512.Size384,
+	"sha512": sha512.Size,
+}
+
+// Digest converts the given "algorithm:hex" string (e.g. "sha256:e3b0...")
+// into a Checksum value.  The algorithm must be one of md5, sha1, sha224,
+// sha256, sha384 or sha512 and the hex digits must be the length of that
+// algorithm's digest.
+// It implements the ValueParser interface.
+func Digest(v string) (interface{}, error) {
+	i := strings.IndexByte(v, ':')
+	if i == -1 {
+		return nil, errors.Errorf(
+			"digest %q is not of the form algorithm:hex", v)
+	}
+	algo := strings.ToLower(v[:i])
+	size, ok := digestSizes[algo]
+	if !ok {
+		return nil, errors.Errorf(
+			"unsupported digest algorithm %q in %q", v[:i], v)
+	}
+	sum, err := hex.DecodeString(v[i+1:])
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid hex digest in %q", v)
+	}
+	if len(sum) != size {
+		return nil, errors.Errorf(
+			"%s digest must be %d hex digits, not %d",
+			algo, 2*size, len(v)-i-1)
+	}
+	return Checksum{Algorithm: algo, Sum: sum}, nil
+}
+
+// LanguageTag converts the given BCP 47 language tag (e.g. "en-US",
+// "zh_hant_tw") into its canonical string form ("en-US", "zh-Hant-TW").
+// Underscores are accepted as separators.  Only the syntax of the tag is
+// validated; the subtags are not checked against the IANA registry.
+// It implements the ValueParser interface.
+func LanguageTag(v string) (interface{}, error) {
+	subtags := strings.Split(strings.ReplaceAll(v, "_", "-"), "-")
+	invalid := func(format string, args ...interface{}) error {
+		return errors.Errorf(
+			"invalid language tag %q: %s", v,
+			fmt.Sprintf(format, args...))
+	}
+	lang := subtags[0]
+	if !isAlpha(lang) || len(lang) < 2 || len(lang) > 8 || len(lang) == 4 {
+		return nil, invalid("bad language subtag %q", lang)
+	}
+	subtags[0] = strings.ToLower(lang)
+	i := 1
+	if i < len(subtags) && len(subtags[i]) == 4 && isAlpha(subtags[i]) {
+		s := strings.ToLower(subtags[i])
+		subtags[i] = strings.ToUpper(s[:1]) + s[1:]
+		i++
+	}
+	if i < len(subtags) {
+		r := subtags[i]
+		if len(r) == 2 && isAlpha(r) || len(r) == 3 && isDigits(r) {
+			subtags[i] = strings.ToUpper(r)
+			i++
+		}
+	}
+	for ; i < len(subtags); i++ {
+		s := strings.ToLower(subtags[i])
+		subtags[i] = s
+		switch {
+		case len(s) == 1:
+			// extension or private use: the rest of the subtags
+			// belong to it.
+			min := 2
+			if s == "x" {
+				min = 1
+			}
+			if i+1 == len(subtags) {
+				return nil, invalid("empty extension %q", s)
+			}
+			for i++; i < len(subtags); i++ {
+				e := strings.ToLower(subtags[i])
+				if len(e) == 1 && s != "x" {
+					i--
+					break
+				}
+				if len(e) < min || len(e) > 8 || !isAlphaNum(e) {
+					return nil, invalid("bad %q extension subtag %q", s, e)
+				}
+				subtags[i] = e
+			}
+		case isAlphaNum(s) && (len(s) >= 5 && len(s) <= 8 ||
+			len(s) == 4 && s[0] >= '0' && s[0] <= '9'):
+			// variant
+		default:
+			return nil, invalid("bad subtag %q", s)
+		}
+	}
+	return strings.Join(subtags, "-"), nil
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isAlphaNum(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// MIMEType converts the given string into a canonical (lowercase) MIME type
+// string like "application/json" or "text/plain; charset=utf-8".
+// It implements the ValueParser interface.
+func MIMEType(v string) (interface{}, error) {
+	t, params, err := mime.ParseMediaType(v)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid MIME type: %q", v)
+	}
+	if strings.Count(t, "/") != 1 || strings.HasPrefix(t, "/") || strings.HasSuffix(t, "/") {
+		return nil, errors.Errorf(
+			"MIME type %q is not of the form type/subtype", v)
+	}
+	return mime.FormatMediaType(t, params), nil
+}
+
+// MIMETypes restricts the Argument's values to the given MIME types (after
+// they're canonicalized by MIMEType) and adds them to the Argument's help
+// and completions.
+func MIMETypes(types ...string) ArgumentOption {
+	return func(a *Argument) error {
+		return restrictStrings(a, MIMEType, types)
+	}
+}
+
+// FileExtension converts the given file extension with or without its
+// leading dot (e.g. "csv" or ".CSV") into its normalized form (".csv").
+// It implements the ValueParser interface.
+func FileExtension(v string) (interface{}, error) {
+	ext := strings.ToLower(strings.TrimPrefix(v, "."))
+	if ext == "" {
+		return nil, errors.Errorf("empty file extension: %q", v)
+	}
+	for _, r := range ext {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' ||
+			r == '.' || r == '-' || r == '_' || r == '+') {
+			return nil, errors.Errorf(
+				"invalid character %q in file extension %q",
+				r, v)
+		}
+	}
+	return "." + ext, nil
+}
+
+// FileExtensions restricts the Argument's values to the given file
+// extensions (after they're normalized by FileExtension) and adds them to
+// the Argument's help and completions.
+func FileExtensions(exts ...string) ArgumentOption {
+	return func(a *Argument) error {
+		return restrictStrings(a, FileExtension, exts)
+	}
+}
+
+// restrictStrings sets the Argument's Type to a ValueParser that normalizes
+// values with parse and only accepts the normalized values of allowed.
+func restrictStrings(a *Argument, parse ValueParser, allowed []string) error {
+	set := make(map[string]struct{}, len(allowed))
+	choices := make([]Choice, 0, len(allowed))
+	for _, v := range allowed {
+		n, err := parse(v)
+		if err != nil {
+			return err
+		}
+		k := n.(string)
+		set[k] = struct{}{}
+		choices = append(choices, Choice{Key: k, Value: k})
+	}
+	if err := Type(func(v string) (interface{}, error) {
+		n, err := parse(v)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := set[n.(string)]; !ok {
+			return nil, errors.Errorf(
+				"%q is not one of: %s", v,
+				strings.Join(keysOf(choices), ", "))
+		}
+		return n, nil
+	})(a); err != nil {
+		return err
+	}
+	a.suggestions = append(a.suggestions, choices...)
+	a.addConstraint("one of: %s", strings.Join(keysOf(choices), ", "))
+	return nil
+}
+
+// keysOf gets the keys of the choices.
+func keysOf(choices []Choice) []string {
+	keys := make([]string, len(choices))
+	for i, c := range choices {
+		keys[i] = c.Key
+	}
+	return keys
+}
+
+// Throughput is an amount of things (or bytes) per duration.
+type Throughput struct {
+	// Amount is the number of things or bytes per Per.
+	Amount float64
+
+	// Bytes is true when the Amount is a number of bytes.
+	Bytes bool
+
+	// Per is the duration that Amount is over.
+	Per time.Duration
+}
+
+// PerSecond gets the amount per second.
+func (t Throughput) PerSecond() float64 {
+	return t.Amount / t.Per.Seconds()
+}
+
+// String formats the throughput the way Rate parses it.
+func (t Throughput) String() string {
+	v := strconv.FormatFloat(t.Amount, 'f', -1, 64)
+	if t.Bytes {
+		v += "B"
+	}
+	return v + "/" + t.Per.String()
+}
+
+// rateUnits maps the names of the durations accepted by Rate to their
+// durations.
+var rateUnits = map[string]time.Duration{
+	"ms": time.Millisecond, "s": time.Second, "sec": time.Second,
+	"second": time.Second, "m": time.Minute, "min": time.Minute,
+	"minute": time.Minute, "h": time.Hour, "hr": time.Hour,
+	"hour": time.Hour, "d": 24 * time.Hour, "day": 24 * time.Hour,
+}
+
+// Rate converts the given "amount/duration" string into a Throughput
+// value.  The amount is a number with an optional decimal (k, M, G, T) or
+// binary (Ki, Mi, Gi, Ti) multiplier and a "B" suffix if it's a number of
+// bytes, e.g. "100/s", "5k/min" or "1.5MB/s".  The duration is a unit name
+// (ms, s, min, h or day) or a time.Duration like "5m".
+// It implements the ValueParser interface.
+func Rate(v string) (interface{}, error) {
+	i := strings.LastIndexByte(v, '/')
+	if i == -1 {
+		return nil, errors.Errorf(
+			"rate %q is not of the form amount/duration", v)
+	}
+	var t Throughput
+	var err error
+	if t.Amount, t.Bytes, err = parseQuantity(v[:i]); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid amount in rate %q", v)
+	}
+	unit := strings.ToLower(v[i+1:])
+	per, ok := rateUnits[unit]
+	if !ok {
+		// plurals like "secs" or "hours"
+		per, ok = rateUnits[strings.TrimSuffix(unit, "s")]
+	}
+	if t.Per = per; !ok {
+		if t.Per, err = time.ParseDuration(unit); err != nil || t.Per <= 0 {
+			return nil, errors.Errorf(
+				"invalid duration %q in rate %q", v[i+1:], v)
+		}
+	}
+	return t, nil
+}
+
+// quantityMultipliers maps the multiplier suffixes accepted by
+// parseQuantity to their values.
+var quantityMultipliers = []struct {
+	suffix string
+	value  float64
+}{
+	// binary multipliers first so that "Ki" isn't mistaken for "i":
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+	{"k", 1e3}, {"K", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+}
+
+// parseQuantity parses a number with an optional multiplier suffix and an
+// optional "B" bytes suffix.
+func parseQuantity(v string) (amount float64, bytes bool, err error) {
+	s := strings.TrimSpace(v)
+	if strings.HasSuffix(s, "B") {
+		bytes = true
+		s = s[:len(s)-1]
+	}
+	multiplier := 1.0
+	for _, m := range quantityMultipliers {
+		if strings.HasSuffix(s, m.suffix) {
+			multiplier = m.value
+			s = s[:len(s)-len(m.suffix)]
+			break
+		}
+	}
+	if amount, err = strconv.ParseFloat(s, 64); err != nil {
+		return 0, false, err
+	}
+	if amount < 0 {
+		return 0, false, errors.Errorf("negative quantity: %q", v)
+	}
+	return amount * multiplier, bytes, nil
+}
+
+// GoTemplate converts the given string into a *template.Template parsed with
+// text/template so that syntax errors are reported (with their positions)
+// when the arguments are parsed instead of when the template is first
+// executed.
+// It implements the ValueParser interface.
+func GoTemplate(v string) (interface{}, error) {
+	return parseGoTemplate(v, nil)
+}
+
+// GoTemplateFuncs creates a ValueParser like GoTemplate whose templates can
+// call the functions in funcs.
+func GoTemplateFuncs(funcs template.FuncMap) ValueParser {
+	return func(v string) (interface{}, error) {
+		return parseGoTemplate(v, funcs)
+	}
+}
+
+func parseGoTemplate(v string, funcs template.FuncMap) (interface{}, error) {
+	t := template.New("argument")
+	if funcs != nil {
+		t = t.Funcs(funcs)
+	}
+	t, err := t.Parse(v)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid template %q", v)
+	}
+	return t, nil
+}
+
+// FieldRange is one item of a FieldSelection: either a named field or an
+// inclusive range of 1-based field numbers.
+type FieldRange struct {
+	// Name is the name of the field or empty if the item is a range.
+	Name string
+
+	// Start and End are the first and last field numbers of the range.
+	// An End of 0 means the range is open-ended (e.g. "3-").
+	Start, End int
+}
+
+// FieldSelection is a comma-separated selection of fields like "1,3-5,name"
+// as accepted by cut(1) or ps(1).
+type FieldSelection []FieldRange
+
+// Selected checks if the field with the given 1-based number and name is
+// selected.
+func (fs FieldSelection) Selected(number int, name string) bool {
+	for _, r := range fs {
+		if r.Name != "" {
+			if r.Name == name {
+				return true
+			}
+			continue
+		}
+		if number >= r.Start && (r.End == 0 || number <= r.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the selection the way Fields parses it.
+func (fs FieldSelection) String() string {
+	parts := make([]string, len(fs))
+	for i, r := range fs {
+		switch {
+		case r.Name != "":
+			parts[i] = r.Name
+		case r.Start == r.End:
+			parts[i] = strconv.Itoa(r.Start)
+		case r.End == 0:
+			parts[i] = strconv.Itoa(r.Start) + "-"
+		default:
+			parts[i] = strconv.Itoa(r.Start) + "-" + strconv.Itoa(r.End)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Fields converts the given comma-separated selection of field numbers,
+// ranges (e.g. "3-5", "3-" or "-5") and names into a FieldSelection value.
+// It implements the ValueParser interface.
+func Fields(v string) (interface{}, error) {
+	return parseFields(v, nil)
+}
+
+// FieldsOf creates a ValueParser like Fields that only accepts the given
+// field names and the numbers of those fields (i.e. 1 through len(names)).
+// The names are also added to the Argument's completions if the parser is
+// set with FieldsOfType.
+func FieldsOf(names ...string) ValueParser {
+	return func(v string) (interface{}, error) {
+		return parseFields(v, names)
+	}
+}
+
+func parseFields(v string, names []string) (interface{}, error) {
+	var fs FieldSelection
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		r, err := parseFieldRange(part)
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "invalid field selection %q", v)
+		}
+		if names != nil {
+			if err := checkFieldRange(r, names); err != nil {
+				return nil, errors.ErrorfWithCause(
+					err, "invalid field selection %q", v)
+			}
+		}
+		fs = append(fs, r)
+	}
+	return fs, nil
+}
+
+func parseFieldRange(part string) (FieldRange, error) {
+	if part == "" {
+		return FieldRange{}, errors.Errorf("empty field")
+	}
+	if !strings.ContainsAny(part[:1], "0123456789-") {
+		return FieldRange{Name: part}, nil
+	}
+	start, end := part, part
+	if i := strings.IndexByte(part, '-'); i != -1 {
+		start, end = part[:i], part[i+1:]
+	}
+	r := FieldRange{Start: 1}
+	var err error
+	if start != "" {
+		if r.Start, err = strconv.Atoi(start); err != nil || r.Start < 1 {
+			return FieldRange{}, errors.Errorf(
+				"invalid field number %q in %q", start, part)
+		}
+	}
+	if end != "" {
+		if r.End, err = strconv.Atoi(end); err != nil || r.End < r.Start {
+			return FieldRange{}, errors.Errorf(
+				"invalid field range %q", part)
+		}
+	} else if start == "" {
+		return FieldRange{}, errors.Errorf(
+			"invalid field range %q", part)
+	}
+	return r, nil
+}
+
+func checkFieldRange(r FieldRange, names []string) error {
+	if r.Name != "" {
+		for _, n := range names {
+			if n == r.Name {
+				return nil
+			}
+		}
+		return errors.Errorf(
+			"unknown field %q (choose from %s)",
+			r.Name, strings.Join(names, ", "))
+	}
+	if r.Start > len(names) || r.End > len(names) {
+		return errors.Errorf(
+			"field numbers must be from 1 to %d", len(names))
+	}
+	return nil
+}
+
+// FieldsOfType sets the Argument's Type to FieldsOf(names...) and adds the
+// names to the Argument's help and completions.
+func FieldsOfType(names ...string) ArgumentOption {
+	return func(a *Argument) error {
+		if err := Type(FieldsOf(names...))(a); err != nil {
+			return err
+		}
+		choices := make([]Choice, len(names))
+		for i, n := range names {
+			choices[i] = Choice{Key: n, Value: n}
+		}
+		a.suggest(choices...)
+		return nil
+	}
+}
+
+// StringSpec creates a ValueParser of strings that must be from minLen to
+// maxLen characters long (a maxLen of 0 means there's no maximum) and, if
+// allowedPattern isn't empty, entirely match the allowedPattern regular
+// expression (e.g. "[a-z][a-z0-9-]*").
+func StringSpec(minLen, maxLen int, allowedPattern string) ValueParser {
+	spec, err := newStringSpec(minLen, maxLen, allowedPattern)
+	if err != nil {
+		return func(v string) (interface{}, error) {
+			return nil, err
+		}
+	}
+	return spec.parse
+}
+
+// StringSpecType sets the Argument's Type to StringSpec(minLen, maxLen,
+// allowedPattern) and adds the restrictions to the Argument's help.
+func StringSpecType(minLen, maxLen int, allowedPattern string) ArgumentOption {
+	return func(a *Argument) error {
+		spec, err := newStringSpec(minLen, maxLen, allowedPattern)
+		if err != nil {
+			return err
+		}
+		if err := Type(spec.parse)(a); err != nil {
+			return err
+		}
+		a.addConstraint("%s", spec.describe())
+		return nil
+	}
+}
+
+// stringSpec holds the restrictions of a StringSpec.
+type stringSpec struct {
+	minLen, maxLen int
+	pattern        string
+	re             *regexp.Regexp
+}
+
+func newStringSpec(minLen, maxLen int, pattern string) (*stringSpec, error) {
+	if minLen < 0 || maxLen < 0 || maxLen != 0 && maxLen < minLen {
+		return nil, errors.Errorf(
+			"invalid string length limits: %d to %d", minLen, maxLen)
+	}
+	spec := &stringSpec{minLen: minLen, maxLen: maxLen, pattern: pattern}
+	if pattern != "" {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "invalid pattern %q", pattern)
+		}
+		spec.re = re
+	}
+	return spec, nil
+}
+
+func (spec *stringSpec) parse(v string) (interface{}, error) {
+	n := utf8.RuneCountInString(v)
+	if n < spec.minLen || spec.maxLen != 0 && n > spec.maxLen {
+		return nil, errors.Errorf(
+			"%q is %d characters long but must be %s",
+			v, n, spec.describeLen())
+	}
+	if spec.re != nil && !spec.re.MatchString(v) {
+		return nil, errors.Errorf(
+			"%q does not match the pattern %s", v, spec.pattern)
+	}
+	return v, nil
+}
+
+func (spec *stringSpec) describeLen() string {
+	switch {
+	case spec.maxLen == 0:
+		return fmt.Sprintf("at least %d characters", spec.minLen)
+	case spec.minLen == spec.maxLen:
+		return fmt.Sprintf("exactly %d characters", spec.minLen)
+	default:
+		return fmt.Sprintf("%d-%d characters", spec.minLen, spec.maxLen)
+	}
+}
+
+func (spec *stringSpec) describe() string {
+	if spec.pattern == "" {
+		return spec.describeLen()
+	}
+	return spec.describeLen() + " matching " + spec.pattern
+}
+
+// IP converts the given string into a net.IP.  Both IPv4 and IPv6 addresses
+// are accepted.
+// It implements the ValueParser interface.
+func IP(v string) (interface{}, error) {
+	ip := net.ParseIP(strings.TrimSpace(v))
+	if ip == nil {
+		return nil, errors.Errorf("invalid IP address: %q", v)
+	}
+	return ip, nil
+}
+
+// CIDR converts the given string (e.g. "10.0.0.0/8") into a *net.IPNet.
+// It implements the ValueParser interface.
+func CIDR(v string) (interface{}, error) {
+	_, n, err := net.ParseCIDR(strings.TrimSpace(v))
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid CIDR network: %q", v)
+	}
+	return n, nil
+}
+
+// URL creates a ValueParser that converts strings into absolute *url.URLs.
+// If any allowedSchemes are given, URLs with other schemes are rejected.
+func URL(allowedSchemes ...string) ValueParser {
+	schemes := make([]string, len(allowedSchemes))
+	for i, s := range allowedSchemes {
+		schemes[i] = strings.ToLower(s)
+	}
+	return func(v string) (interface{}, error) {
+		u, err := url.Parse(strings.TrimSpace(v))
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "invalid URL: %q", v)
+		}
+		if u.Scheme == "" {
+			return nil, errors.Errorf(
+				"URL %q has no scheme", v)
+		}
+		if len(schemes) == 0 {
+			return u, nil
+		}
+		for _, s := range schemes {
+			if u.Scheme == s {
+				return u, nil
+			}
+		}
+		return nil, errors.Errorf(
+			"scheme %q of URL %q is not one of: %s",
+			u.Scheme, v, strings.Join(schemes, ", "))
+	}
+}
+
+// ByteSize converts the given human-readable size into an int64 number of
+// bytes.  The size is a number with an optional decimal (k, M, G, T) or
+// binary (Ki, Mi, Gi, Ti) multiplier and an optional "B" suffix, e.g.
+// "512", "10K", "1.5GB" or "64MiB".
+// It implements the ValueParser interface.
+func ByteSize(v string) (interface{}, error) {
+	amount, _, err := parseQuantity(v)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid byte size: %q", v)
+	}
+	if amount >= math.MaxInt64 {
+		return nil, errors.Errorf("byte size %q is too large", v)
+	}
+	if amount != math.Trunc(amount) {
+		return nil, errors.Errorf(
+			"byte size %q is not a whole number of bytes", v)
+	}
+	return int64(amount), nil
+}
+
+// UUID converts the given UUID, with or without dashes and in any case,
+// into its canonical form, e.g. "123e4567-e89b-12d3-a456-426614174000".
+// It implements the ValueParser interface.
+func UUID(v string) (interface{}, error) {
+	b, err := parseUUID(v)
+	if err != nil {
+		return nil, err
+	}
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// UUIDBytes converts the given UUID, with or without dashes and in any case,
+// into its [16]byte value.
+// It implements the ValueParser interface.
+func UUIDBytes(v string) (interface{}, error) {
+	return parseUUID(v)
+}
+
+func parseUUID(v string) (b [16]byte, err error) {
+	s := strings.TrimSpace(v)
+	if len(s) == 36 {
+		for _, i := range [...]int{8, 13, 18, 23} {
+			if s[i] != '-' {
+				return b, errors.Errorf(
+					"invalid UUID %q: expected '-' at "+
+						"index %d", v, i)
+			}
+		}
+		s = strings.Replace(s, "-", "", 4)
+	}
+	if len(s) != 32 {
+		return b, errors.Errorf(
+			"invalid UUID %q: expected 32 hex digits", v)
+	}
+	if _, err = hex.Decode(b[:], []byte(s)); err != nil {
+		return b, errors.ErrorfWithCause(err, "invalid UUID %q", v)
+	}
+	return b, nil
+}
+
+// HexBytes converts the given hexadecimal string (optionally prefixed with
+// "0x") into a []byte.
+// It implements the ValueParser interface.
+func HexBytes(v string) (interface{}, error) {
+	s := strings.TrimSpace(v)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid hexadecimal bytes: %q", v)
+	}
+	return b, nil
+}
+
+// base64Encodings are the encodings tried by Base64Bytes.
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding, base64.RawStdEncoding,
+	base64.URLEncoding, base64.RawURLEncoding,
+}
+
+// Base64Bytes converts the given base64 string into a []byte.  The standard
+// and URL-safe alphabets are accepted with or without padding.
+// It implements the ValueParser interface.
+func Base64Bytes(v string) (interface{}, error) {
+	s := strings.TrimSpace(v)
+	var err error
+	for _, enc := range base64Encodings {
+		var b []byte
+		if b, err = enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, errors.ErrorfWithCause(
+		err, "invalid base64 bytes: %q", v)
+}
+
+// JSON unmarshals the given string as JSON into an interface{} (i.e.
+// map[string]interface{}, []interface{}, float64, string, bool or nil).
+// It implements the ValueParser interface.
+func JSON(v string) (interface{}, error) {
+	var x interface{}
+	if err := json.Unmarshal([]byte(v), &x); err != nil {
+		return nil, errors.ErrorfWithCause(err, "invalid JSON: %q", v)
+	}
+	return x, nil
+}
+
+// JSONInto creates a ValueParser that unmarshals JSON into new values of
+// the same type as prototype.  If prototype is a pointer, the parsed values
+// are pointers to new values of the type it points to.
+func JSONInto(prototype interface{}) ValueParser {
+	t := reflect.TypeOf(prototype)
+	ptr := t.Kind() == reflect.Ptr
+	if ptr {
+		t = t.Elem()
+	}
+	return func(v string) (interface{}, error) {
+		pv := reflect.New(t)
+		if err := json.Unmarshal([]byte(v), pv.Interface()); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "invalid JSON %v: %q", t, v)
+		}
+		if ptr {
+			return pv.Interface(), nil
+		}
+		return pv.Elem().Interface(), nil
+	}
+}
+
+// FileType creates a ValueParser that opens the named file with os.OpenFile
+// and the given flag and perm so that the argument's value is an *os.File.
+// The name "-" is os.Stdin if the flag opens files read-only or os.Stdout
+// otherwise.  Closing the files is up to the caller.
+func FileType(flag int, perm os.FileMode) ValueParser {
+	return func(v string) (interface{}, error) {
+		if v == "-" {
+			if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+				return os.Stdin, nil
+			}
+			return os.Stdout, nil
+		}
+		f, err := os.OpenFile(v, flag, perm)
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "failed to open %q", v)
+		}
+		return f, nil
+	}
+}
+
+// ExistingFile checks that the given path names an existing file that isn't
+// a directory.
+// It implements the ValueParser interface.
+func ExistingFile(v string) (interface{}, error) {
+	fi, err := os.Stat(v)
+	if err != nil {
+		return nil, pathError(v, "file", err)
+	}
+	if fi.IsDir() {
+		return nil, errors.Errorf("%q is a directory, not a file", v)
+	}
+	return v, nil
+}
+
+// ExistingDir checks that the given path names an existing directory.
+// It implements the ValueParser interface.
+func ExistingDir(v string) (interface{}, error) {
+	fi, err := os.Stat(v)
+	if err != nil {
+		return nil, pathError(v, "directory", err)
+	}
+	if !fi.IsDir() {
+		return nil, errors.Errorf("%q is not a directory", v)
+	}
+	return v, nil
+}
+
+// WritablePath checks that the given path is either an existing file that
+// can be opened for writing or a file that can be created in an existing
+// directory.  Nothing is written or created: if the file doesn't exist, only
+// the directory's permission bits are checked for write permission, so the
+// check can pass for a directory that only another user can write to.
+// It implements the ValueParser interface.
+func WritablePath(v string) (interface{}, error) {
+	fi, err := os.Stat(v)
+	switch {
+	case err == nil:
+		if fi.IsDir() {
+			return nil, errors.Errorf(
+				"%q is a directory, not a file", v)
+		}
+		f, err := os.OpenFile(v, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "%q is not writable", v)
+		}
+		f.Close()
+		return v, nil
+	case !os.IsNotExist(err):
+		return nil, pathError(v, "file", err)
+	}
+	dir := filepath.Dir(v)
+	if _, err := ExistingDir(dir); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "cannot create %q", v)
+	}
+	if fi, err = os.Stat(dir); err != nil {
+		return nil, pathError(dir, "directory", err)
+	}
+	if fi.Mode().Perm()&0o222 == 0 {
+		return nil, errors.Errorf(
+			"cannot create %q: directory %q is not writable",
+			v, dir)
+	}
+	return v, nil
+}
+
+// pathError describes why the path of the given kind couldn't be stat'ed.
+func pathError(path, kind string, err error) error {
+	switch {
+	case os.IsNotExist(err):
+		return errors.Errorf("%s %q does not exist", kind, path)
+	case os.IsPermission(err):
+		return errors.ErrorfWithCause(
+			err, "permission denied accessing %s %q", kind, path)
+	}
+	return errors.ErrorfWithCause(err, "cannot access %s %q", kind, path)
+}
+
+// Glob expands the given glob pattern (see filepath.Match) into a []string
+// of the paths that it matches.  Values without any pattern characters are
+// returned as-is whether or not they exist, but it's an error for a pattern
+// to match nothing.
+// It implements the ValueParser interface.
+func Glob(v string) (interface{}, error) {
+	if !strings.ContainsAny(v, `*?[`) {
+		return []string{v}, nil
+	}
+	paths, err := filepath.Glob(v)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "invalid glob pattern %q", v)
+	}
+	if len(paths) == 0 {
+		return nil, errors.Errorf("no paths match %q", v)
+	}
+	return paths, nil
+}
+
+// Enum creates a ValueParser that converts the names in values to the
+// values they map to.  Other names are rejected with an error that lists
+// the valid names.
+func Enum(values map[string]interface{}) ValueParser {
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return func(v string) (interface{}, error) {
+		if x, ok := values[v]; ok {
+			return x, nil
+		}
+		return nil, errors.Errorf(
+			"invalid value %q (choose from %s)",
+			v, strings.Join(names, ", "))
+	}
+}
+
+// IntRange creates a ValueParser like Int that rejects values outside of
+// [min, max] with a *RangeError.
+func IntRange(min, max int) ValueParser {
+	desc := fmt.Sprintf("[%d, %d]", min, max)
+	return func(v string) (interface{}, error) {
+		x, err := Int(v)
+		if err != nil {
+			return nil, err
+		}
+		i := x.(int)
+		switch {
+		case i < min:
+			return nil, &RangeError{Value: i, Nearest: min, Range: desc}
+		case i > max:
+			return nil, &RangeError{Value: i, Nearest: max, Range: desc}
+		}
+		return i, nil
+	}
+}
+
+// IntRangeType sets the Argument's Type to IntRange(min, max) and adds the
+// range to the Argument's help.  If the Argument is Clamped, out-of-range
+// values are replaced with the nearest bound.
+func IntRangeType(min, max int) ArgumentOption {
+	return func(a *Argument) error {
+		if max < min {
+			return errors.Errorf("invalid range: [%d, %d]", min, max)
+		}
+		if err := Type(IntRange(min, max))(a); err != nil {
+			return err
+		}
+		a.typ = reflect.TypeOf(0)
+		a.addConstraint("between %d and %d", min, max)
+		return nil
+	}
+}
+
+// Float64Range creates a ValueParser like Float64 that rejects values
+// outside of [min, max] with a *RangeError.
+func Float64Range(min, max float64) ValueParser {
+	desc := fmt.Sprintf("[%v, %v]", min, max)
+	return func(v string) (interface{}, error) {
+		x, err := Float64(v)
+		if err != nil {
+			return nil, err
+		}
+		f := x.(float64)
+		switch {
+		case f < min:
+			return nil, &RangeError{Value: f, Nearest: min, Range: desc}
+		case f > max:
+			return nil, &RangeError{Value: f, Nearest: max, Range: desc}
+		case math.IsNaN(f):
+			return nil, errors.Errorf("NaN is not in range %s", desc)
+		}
+		return f, nil
+	}
+}
+
+// Float64RangeType sets the Argument's Type to Float64Range(min, max) and
+// adds the range to the Argument's help.  If the Argument is Clamped,
+// out-of-range values are replaced with the nearest bound.
+func Float64RangeType(min, max float64) ArgumentOption {
+	return func(a *Argument) error {
+		if !(min <= max) {
+			return errors.Errorf("invalid range: [%v, %v]", min, max)
+		}
+		if err := Type(Float64Range(min, max))(a); err != nil {
+			return err
+		}
+		a.typ = reflect.TypeOf(0.0)
+		a.addConstraint("between %v and %v", min, max)
+		return nil
+	}
+}
+
+// BigInt converts the given string into a *big.Int.  Like Go integer
+// literals, it can have a 0b, 0o or 0x base prefix and underscores, but a
+// leading 0 without a prefix is decimal, not octal.
+// It implements the ValueParser interface.
+func BigInt(v string) (interface{}, error) {
+	v = strings.TrimSpace(v)
+	i, ok := new(big.Int).SetString(v, intBase(v))
+	if !ok {
+		return nil, errors.Errorf("invalid integer: %q", v)
+	}
+	return i, nil
+}
+
+// BigRat converts the given fraction (e.g. "1/3") or decimal number (e.g.
+// "0.1" or "1e-3") into an exact *big.Rat.
+// It implements the ValueParser interface.
+func BigRat(v string) (interface{}, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(v))
+	if !ok {
+		return nil, errors.Errorf("invalid rational number: %q", v)
+	}
+	return r, nil
+}
+
+// Port creates a ValueParser that converts strings into uint16 port numbers.
+// Port 0 is always rejected and unless allowPrivileged is true, so are the
+// privileged ports below 1024.
+func Port(allowPrivileged bool) ValueParser {
+	return func(v string) (interface{}, error) {
+		x, err := Uint16(strings.TrimSpace(v))
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "invalid port: %q", v)
+		}
+		port := x.(uint16)
+		switch {
+		case port == 0:
+			return nil, errors.Errorf("invalid port: 0")
+		case port < 1024 && !allowPrivileged:
+			return nil, errors.Errorf(
+				"port %d is privileged; use a port from 1024 "+
+					"to 65535", port)
+		}
+		return port, nil
+	}
+}
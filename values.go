@@ -0,0 +1,165 @@
+package argparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/skillian/errors"
+)
+
+// ParseValues evaluates values from a query string or form against the
+// parser's arguments.  Each argument's values are looked up by the
+// argument's Dest and they go through the same type conversion, choices,
+// defaults and required checks that parsing the command line does.  Because
+// the values come from a request and not from a terminal, arguments with a
+// Confirm prompt that are missing are an error instead of being prompted
+// for, and bound targets aren't assigned so that handling requests
+// concurrently doesn't change shared variables.  Use the returned Namespace
+// instead.
+//
+// An argument that accepts no values (e.g. a StoreTrue argument) is given if
+// its key has any value other than one that parses as false.  Every other
+// value of an optional argument is treated as a separate occurrence of the
+// argument unless the argument accepts a variable number of values, in which
+// case all the values belong to one occurrence.
+func (p *ArgumentParser) ParseValues(values url.Values) (Namespace, error) {
+	tokens, err := p.valuesTokens(values)
+	if err != nil {
+		return nil, err
+	}
+	return p.evaluateTokens(tokens, true)
+}
+
+// ParseMap evaluates the values in m, keyed by argument Dest, against the
+// parser's arguments the same way as ParseValues.
+func (p *ArgumentParser) ParseMap(m map[string]string) (Namespace, error) {
+	values := make(url.Values, len(m))
+	for k, v := range m {
+		values[k] = []string{v}
+	}
+	return p.ParseValues(values)
+}
+
+// ParseJSON evaluates a JSON object, keyed by argument Dest, against the
+// parser's arguments the same way as ParseValues.  The object's values must
+// be strings, numbers, booleans, null (which is the same as leaving the key
+// out) or arrays of those values.
+func (p *ArgumentParser) ParseJSON(data json.RawMessage) (Namespace, error) {
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err, "failed to decode JSON parameters")
+	}
+	values := make(url.Values, len(m))
+	for k, v := range m {
+		vs, ok := v.([]interface{})
+		if !ok {
+			if v == nil {
+				continue
+			}
+			vs = []interface{}{v}
+		}
+		for _, v := range vs {
+			switch v.(type) {
+			case string, json.Number, bool:
+				values[k] = append(values[k], fmt.Sprint(v))
+			default:
+				return nil, errors.Errorf(
+					"unsupported value %v (type: %T) "+
+						"of parameter %q", v, v, k)
+			}
+		}
+	}
+	return p.ParseValues(values)
+}
+
+// valuesTokens creates the tokens that Evaluate needs from values.
+func (p *ArgumentParser) valuesTokens(values url.Values) ([]Token, error) {
+	dests := make(map[string]struct{}, len(p.args))
+	var tokens []Token
+	for _, a := range p.args {
+		if a.Action == ShowHelp || a.Action == ShowVersion {
+			continue
+		}
+		dests[a.Dest] = struct{}{}
+		vs, ok := values[a.Dest]
+		if !ok {
+			continue
+		}
+		if !a.Optional() {
+			for _, v := range vs {
+				tokens = append(tokens, Token{
+					Kind:     TokenPositional,
+					Value:    v,
+					Argument: a,
+				})
+			}
+			continue
+		}
+		option := Token{
+			Kind:     TokenOption,
+			Value:    a.OptionStrings[0],
+			Argument: a,
+		}
+		switch a.Nargs {
+		case 0:
+			for _, v := range vs {
+				if b, err := Bool(v); err == nil && b == false {
+					if len(a.negations) > 0 {
+						negation := option
+						negation.Value = a.negations[0]
+						tokens = append(tokens, negation)
+					}
+					continue
+				}
+				tokens = append(tokens, option)
+			}
+		case 1, ZeroOrOne:
+			for _, v := range vs {
+				tokens = append(tokens, option, Token{
+					Kind:     TokenValue,
+					Value:    v,
+					Argument: a,
+				})
+			}
+		default:
+			size := len(vs)
+			if a.Nargs > 0 {
+				if len(vs)%a.Nargs != 0 {
+					return nil, errors.Errorf(
+						"%d values for argument %q is "+
+							"not a multiple of %d",
+						len(vs), a.Dest, a.Nargs)
+				}
+				size = a.Nargs
+			}
+			for i, v := range vs {
+				if i%size == 0 {
+					tokens = append(tokens, option)
+				}
+				tokens = append(tokens, Token{
+					Kind:     TokenValue,
+					Value:    v,
+					Argument: a,
+				})
+			}
+		}
+	}
+	var unexpected []string
+	for k := range values {
+		if _, ok := dests[k]; !ok {
+			unexpected = append(unexpected, k)
+		}
+	}
+	if len(unexpected) > 0 {
+		sort.Strings(unexpected)
+		return nil, errors.Errorf(
+			"unexpected parameters: %q", unexpected)
+	}
+	return tokens, nil
+}